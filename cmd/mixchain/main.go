@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/node"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	datadir   = flag.String("datadir", "", "链数据库(LevelDB)所在目录, 为空时使用内存数据库")
+	genesis   = flag.String("genesis", "", "创世块配置(JSON)文件, 为空时使用开发链配置")
+	devAddr   = flag.String("dev.addr", "", "开发链创世块中预先分配余额的账户地址")
+	httpAddr  = flag.String("http.addr", node.DefaultConfig.HTTPAddr, "HTTP和WebSocket JSON-RPC服务的监听地址")
+	httpCors  = flag.String("http.corsdomain", "", "允许跨域请求的来源列表(逗号分隔)")
+	wsOrigins = flag.String("ws.origins", "", "允许WebSocket连接的来源列表(逗号分隔)")
+	gasCap    = flag.Uint64("rpc.gascap", node.DefaultConfig.RPCGasCap, "eth_call和eth_estimateGas可使用的gas上限(0为不限制)")
+	coinbase  = flag.String("miner.etherbase", "", "接收出块交易小费的账户地址")
+	verbosity = flag.Int("verbosity", 3, "日志级别: 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=detail")
+)
+
+func main() {
+	flag.Parse()
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.FromLegacyLevel(*verbosity), true)))
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Fatal:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	db, err := openDatabase(*datadir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	spec, err := loadGenesis(*genesis)
+	if err != nil {
+		return err
+	}
+	config := node.DefaultConfig
+	config.HTTPAddr = *httpAddr
+	config.HTTPCors = splitList(*httpCors)
+	config.WSOrigins = splitList(*wsOrigins)
+	config.RPCGasCap = *gasCap
+	config.Miner.Coinbase = common.HexToAddress(*coinbase)
+
+	stack, err := node.New(db, spec, config)
+	if err != nil {
+		return err
+	}
+	if err := stack.Start(); err != nil {
+		return err
+	}
+	defer stack.Close()
+
+	// 等待中断信号后退出
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Got interrupt, shutting down...")
+	return nil
+}
+
+func openDatabase(datadir string) (ethdb.KeyValueStore, error) {
+	if datadir == "" {
+		return rawdb.NewMemoryDatabase(), nil
+	}
+	return rawdb.NewLevelDBDatabase(datadir, 16, 16, "", false)
+}
+
+// loadGenesis 读取创世块配置, 未指定文件时返回开发链配置
+func loadGenesis(path string) (*mvm.Genesis, error) {
+	if path == "" {
+		return devGenesis(), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	spec := new(mvm.Genesis)
+	if err := json.NewDecoder(file).Decode(spec); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return spec, nil
+}
+
+func devGenesis() *mvm.Genesis {
+	alloc := types.GenesisAlloc{}
+	if *devAddr != "" {
+		balance := new(big.Int).Lsh(big.NewInt(1), 255)
+		alloc[common.HexToAddress(*devAddr)] = types.Account{Balance: balance}
+	}
+	return &mvm.Genesis{
+		Config:   params.AllDevChainProtocolChanges,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+		Alloc:    alloc,
+	}
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	list := strings.Split(value, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"encoding/hex"
+	"flag"
 	"fmt"
 	"github.com/a1146910248/mixchain/mvm"
 	"github.com/a1146910248/mixchain/mvm/abi"
+	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/mock"
 	"github.com/a1146910248/mixchain/mvm/params"
 	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracers/logger"
+	"github.com/a1146910248/mixchain/mvm/tracers/profiler"
 	"github.com/a1146910248/mixchain/mvm/vm"
-	"github.com/holiman/uint256"
+	"io"
 	"math/big"
+	"os"
 	"reflect"
 	"strings"
 )
@@ -18,25 +23,94 @@ import (
 // 2e64cec1 : retrieve, 6057361d000000000000000000000000000000000000000000000000000000000000007b: store 123
 var input, _ = hex.DecodeString("2e64cec1")
 
+var (
+	datadir  = flag.String("datadir", "", "状态数据库(LevelDB)所在目录, 为空时使用内存数据库")
+	root     = flag.String("root", "", "在指定的历史状态根上执行(只读, 不会提交)")
+	history  = flag.Bool("history", false, "按提交顺序打印所有历史状态根后退出")
+	rollback = flag.String("rollback", "", "将head回滚到指定的历史状态根后退出")
+
+	trace          = flag.Bool("trace", false, "以EIP-3155格式(每行一个JSON)向stderr输出逐条指令的执行轨迹")
+	traceMemory    = flag.Bool("trace.memory", false, "执行轨迹中包含内存")
+	traceNoStack   = flag.Bool("trace.nostack", false, "执行轨迹中不包含栈")
+	traceNoStorage = flag.Bool("trace.nostorage", false, "执行轨迹中不包含存储变化")
+
+	profileFolded = flag.String("profile.folded", "", "按调用栈汇总gas, 以folded stacks格式写入指定文件, 可用flamegraph.pl生成火焰图")
+	profilePprof  = flag.String("profile.pprof", "", "按调用栈汇总gas和耗时, 以pprof protobuf格式写入指定文件, 可用go tool pprof查看")
+)
+
 func main() {
-	// return
-	//updateContract()
+	flag.Parse()
+	profiling := *profileFolded != "" || *profilePprof != ""
+	if *trace && profiling {
+		fmt.Fprintln(os.Stderr, "-trace不能和-profile.*同时使用")
+		os.Exit(2)
+	}
 	// 创建账户State
-	stateDb, err := state.TryLoadFromDisk()
+	stateDb, err := openState(*datadir)
+	if err != nil {
+		panic(err)
+	}
+	defer stateDb.Database().DiskDB().Close()
+	switch {
+	case *history:
+		for i, r := range state.History(stateDb.Database()) {
+			fmt.Printf("%d: %x\n", i, r)
+		}
+		return
+	case *rollback != "":
+		fmt.Println(state.Rollback(stateDb.Database(), common.HexToHash(*rollback)))
+		return
+	case *root != "":
+		if stateDb, err = state.New(common.HexToHash(*root), stateDb.Database()); err != nil {
+			panic(err)
+		}
+	}
+	if stateDb.GetCodeSize(helloWorldcontactAccont) == 0 {
+		updateContract(stateDb)
+	}
+	msg := mock.GetMessage(normalAccount)
+	msg.To = &helloWorldcontactAccont
+	msg.Nonce = stateDb.GetNonce(normalAccount)
+	msg.GasLimit = 1000000
+	msg.Data = input
+
+	blockCtx := mvm.NewEVMBlockContext(mock.GetHeader(100, 1, 1200000), nil)
+	txCtx := mvm.NewEVMTxContext(msg)
+	var vmConfig vm.Config
+	if *trace {
+		vmConfig.Tracer = logger.NewJSONLogger(&logger.Config{
+			EnableMemory:   *traceMemory,
+			DisableStack:   *traceNoStack,
+			DisableStorage: *traceNoStorage,
+		}, os.Stderr)
+	}
+	var prof *profiler.Profiler
+	if profiling {
+		prof = profiler.New()
+		vmConfig.Tracer = prof.Hooks()
+	}
+	var evmState vm.StateDB = stateDb
+	if vmConfig.Tracer != nil {
+		evmState = state.NewHookedState(stateDb, vmConfig.Tracer)
+	}
+	vmenv := vm.NewEVM(blockCtx, txCtx, evmState, params.AllEthashProtocolChanges, vmConfig)
+	if vmConfig.Tracer != nil && vmConfig.Tracer.OnTxStart != nil {
+		// ApplyMessage不会触发OnTxStart, 需要手动提供执行环境
+		vmConfig.Tracer.OnTxStart(vmenv.GetVMContext(), nil, msg.From)
+	}
+
+	result, err := mvm.ApplyMessage(vmenv, msg, new(mvm.GasPool).AddGas(blockCtx.GasLimit))
 	if err != nil {
 		panic(err)
 	}
-	blockCtx := mvm.NewEVMBlockContext(mock.GetHeader(100, 1, 1200000))
-	txCtx := mvm.NewEVMTxContext(mock.GetMessage(normalAccount))
-	vmenv := vm.NewEVM(blockCtx, txCtx, stateDb, params.AllEthashProtocolChanges, vm.Config{})
-
-	//ret, caddr, leftgas, err := vmenv.Create(vm.AccountRef(normalAccount), helloCode, 1000000, new(uint256.Int))
-	//fmt.Printf("usedGas: %v,caddr:%v, err: %v, len(ret): %v \n", 1000000-leftgas, caddr, err, len(ret))
-	ret, leftgas, err := vmenv.Call(vm.AccountRef(normalAccount), helloWorldcontactAccont, input, 1000000, new(uint256.Int))
-	//appen := make([]byte, 16)
-	//ret = append(ret, appen...)
-	fmt.Printf("usedGas: %v, err: %v, len(ret): %v \n", 1000000-leftgas, err, len(ret))
-	//fmt.Printf("ret: %v, usedGas: %v, err: %v, len(ret): %v, hexret: %v, ", ret, 1000000-leftgas, err, len(ret), hex.EncodeToString(ret))
+	ret := result.ReturnData
+	fmt.Printf("usedGas: %v, err: %v, len(ret): %v \n", result.UsedGas, result.Err, len(ret))
+	if prof != nil {
+		if err := writeProfile(prof); err != nil {
+			panic(err)
+		}
+	}
+	//fmt.Printf("ret: %v, usedGas: %v, err: %v, len(ret): %v, hexret: %v, ", ret, result.UsedGas, result.Err, len(ret), hex.EncodeToString(ret))
 	abiObjet, _ := abi.JSON(strings.NewReader(storeContractABIJson))
 
 	// begin, length, _ := lengthPrefixPointsTo(0, ret)
@@ -45,7 +119,44 @@ func main() {
 	fmt.Println(abiObjet.UnpackIntoInterface(&value, "retrieve", ret))
 	//fmt.Println(unpackAtomic(&restult, string(ret[begin:begin+length])))
 	println(value.String())
-	fmt.Println(stateDb.Commit())
+	if !stateDb.ReadOnly() {
+		fmt.Println(stateDb.Commit(false))
+	}
+}
+
+// writeProfile 将gas profile写入-profile.folded和-profile.pprof指定的文件
+func writeProfile(prof *profiler.Profiler) error {
+	for _, out := range []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{*profileFolded, prof.WriteFolded},
+		{*profilePprof, prof.WriteProfile},
+	} {
+		if out.path == "" {
+			continue
+		}
+		f, err := os.Create(out.path)
+		if err != nil {
+			return err
+		}
+		if err := out.write(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openState 打开datadir下最近一次提交的状态, datadir为空时使用内存数据库
+func openState(datadir string) (*state.StateDB, error) {
+	if datadir == "" {
+		return state.NewAccountStateDb(), nil
+	}
+	return state.Open(datadir)
 }
 
 func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err error) {
@@ -2,9 +2,11 @@ package main
 
 import (
 	"encoding/hex"
-	"fmt"
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/holiman/uint256"
+	"math/big"
 )
 
 var normalAddress, _ = hex.DecodeString("123456abc")
@@ -23,15 +25,12 @@ var hellCodeStr = "608060405234801561001057600080fd5b50600436106100365760003560e
 var helloCode, _ = hex.DecodeString(hellCodeStr)
 var baseCode, _ = hex.DecodeString(baseCodeStr)
 
-func updateContract() {
-	// 加载账户State
-	stateDb, err := state.TryLoadFromDisk()
-	if err != nil {
-		panic(err)
-	}
+// updateContract 部署测试合约, 将helloworld合约的存储初始化为123, 并给普通账户转入用于支付gas的余额
+func updateContract(stateDb *state.StateDB) {
+	stateDb.AddBalance(normalAccount, uint256.NewInt(1e18), tracing.BalanceChangeUnspecified)
 	stateDb.SetCode(helloWorldcontactAccont, helloCode)
 	stateDb.SetCode(baseContractAccont, baseCode)
-	fmt.Println(stateDb.Commit())
+	stateDb.SetState(helloWorldcontactAccont, common.Hash{}, common.BigToHash(big.NewInt(123)))
 }
 
 var baseContractABIJson = `[
@@ -24,8 +24,8 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/a1146910248/mixchain/crypto/secp256k1"
 	"github.com/a1146910248/mixchain/mvm/common/math"
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 )
 
 // Ecrecover returns the uncompressed public key that created the given signature.
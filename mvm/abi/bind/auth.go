@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/external"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -63,9 +64,9 @@ func NewKeyStoreTransactor(keystore *keystore.KeyStore, account accounts.Account
 	log.Warn("WARNING: NewKeyStoreTransactor has been deprecated in favour of NewTransactorWithChainID")
 	signer := types.HomesteadSigner{}
 	return &TransactOpts{
-		From: account.Address,
+		From: common.Address(account.Address),
 		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
-			if address != account.Address {
+			if address != common.Address(account.Address) {
 				return nil, ErrNotAuthorized
 			}
 			signature, err := keystore.SignHash(account, signer.Hash(tx).Bytes())
@@ -124,9 +125,9 @@ func NewKeyStoreTransactorWithChainID(keystore *keystore.KeyStore, account accou
 	}
 	signer := types.LatestSignerForChainID(chainID)
 	return &TransactOpts{
-		From: account.Address,
+		From: common.Address(account.Address),
 		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
-			if address != account.Address {
+			if address != common.Address(account.Address) {
 				return nil, ErrNotAuthorized
 			}
 			signature, err := keystore.SignHash(account, signer.Hash(tx).Bytes())
@@ -167,12 +168,29 @@ func NewKeyedTransactorWithChainID(key *ecdsa.PrivateKey, chainID *big.Int) (*Tr
 // with a clef backend.
 func NewClefTransactor(clef *external.ExternalSigner, account accounts.Account) *TransactOpts {
 	return &TransactOpts{
-		From: account.Address,
+		From: common.Address(account.Address),
 		Signer: func(address common.Address, transaction *types.Transaction) (*types.Transaction, error) {
-			if address != account.Address {
+			if address != common.Address(account.Address) {
 				return nil, ErrNotAuthorized
 			}
-			return clef.SignTx(account, transaction, nil) // Clef enforces its own chain id
+			// Clef signs go-ethereum transactions, convert through the canonical encoding.
+			enc, err := transaction.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			unsigned := new(gethtypes.Transaction)
+			if err := unsigned.UnmarshalBinary(enc); err != nil {
+				return nil, err
+			}
+			signed, err := clef.SignTx(account, unsigned, nil) // Clef enforces its own chain id
+			if err != nil {
+				return nil, err
+			}
+			if enc, err = signed.MarshalBinary(); err != nil {
+				return nil, err
+			}
+			tx := new(types.Transaction)
+			return tx, tx.UnmarshalBinary(enc)
 		},
 		Context: context.Background(),
 	}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/a1146910248/mixchain"
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/types"
 )
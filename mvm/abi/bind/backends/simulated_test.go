@@ -0,0 +1,325 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/a1146910248/mixchain"
+	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/hexutil"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+
+	// returnCode returns the word 42.
+	returnCode = []byte{
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}
+	// logTopic is the topic of the single log emitted by logCode.
+	logTopic = common.HexToHash("0xa1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1")
+	logCode  = append(append([]byte{byte(vm.PUSH32)}, logTopic.Bytes()...),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.LOG1), byte(vm.STOP))
+)
+
+// revertCode reverts with the abi encoded Error(string) of the given reason.
+func revertCode(reason string) []byte {
+	payload := common.FromHex("0x08c379a0")
+	payload = append(payload, common.LeftPadBytes([]byte{0x20}, 32)...)
+	payload = append(payload, common.LeftPadBytes(big.NewInt(int64(len(reason))).Bytes(), 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), (len(reason)+31)/32*32)...)
+
+	return append([]byte{
+		byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0x00, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), 0x00, byte(vm.REVERT),
+	}, payload...)
+}
+
+// deployCode wraps the runtime code into init code returning it.
+func deployCode(runtime []byte) []byte {
+	return append([]byte{
+		byte(vm.PUSH1), byte(len(runtime)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0x00, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(runtime)), byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}, runtime...)
+}
+
+func newTestBackend() *SimulatedBackend {
+	return NewSimulatedBackend(types.GenesisAlloc{testAddr: {Balance: testBalance}}, 10000000)
+}
+
+// newTx signs a dynamic fee transaction from testAddr with the next pending nonce.
+func newTx(t *testing.T, sim *SimulatedBackend, to *common.Address, value *big.Int, gas uint64, data []byte) *types.Transaction {
+	t.Helper()
+
+	nonce, err := sim.PendingNonceAt(context.Background(), testAddr)
+	if err != nil {
+		t.Fatalf("could not get nonce: %v", err)
+	}
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+	tx, err := types.SignNewTx(testKey, types.LatestSigner(sim.config), &types.DynamicFeeTx{
+		ChainID:   sim.config.ChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: new(big.Int).Mul(head.BaseFee, big.NewInt(2)),
+		Gas:       gas,
+		To:        to,
+		Value:     value,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("could not sign tx: %v", err)
+	}
+	return tx
+}
+
+// deploy sends a creation transaction for the runtime code and commits it.
+func deploy(t *testing.T, sim *SimulatedBackend, runtime []byte) common.Address {
+	t.Helper()
+
+	tx := newTx(t, sim, nil, new(big.Int), 100000, deployCode(runtime))
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("could not send deploy tx: %v", err)
+	}
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("could not get receipt: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("deployment failed")
+	}
+	return receipt.ContractAddress
+}
+
+func TestSimulatedBackendCommit(t *testing.T) {
+	t.Parallel()
+	sim := newTestBackend()
+	defer sim.Close()
+	ctx := context.Background()
+
+	to := common.HexToAddress("0x0102")
+	tx := newTx(t, sim, &to, big.NewInt(1000), params.TxGas, nil)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("could not send tx: %v", err)
+	}
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || !pending {
+		t.Fatalf("tx not pending: pending %v, err %v", pending, err)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, testAddr); nonce != 1 {
+		t.Fatalf("pending nonce mismatch: have %d, want 1", nonce)
+	}
+	if _, err := sim.TransactionReceipt(ctx, tx.Hash()); !errors.Is(err, ethereum.NotFound) {
+		t.Fatalf("receipt of pending tx: have %v, want %v", err, ethereum.NotFound)
+	}
+	hash := sim.Commit()
+
+	block, err := sim.BlockByNumber(ctx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("could not get block: %v", err)
+	}
+	if block.Hash() != hash || len(block.Transactions()) != 1 {
+		t.Fatalf("committed block mismatch: hash %x, txs %d", block.Hash(), len(block.Transactions()))
+	}
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || pending {
+		t.Fatalf("tx still pending: pending %v, err %v", pending, err)
+	}
+	receipt, err := sim.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("could not get receipt: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful || receipt.BlockHash != hash {
+		t.Fatalf("receipt mismatch: status %d, block %x", receipt.Status, receipt.BlockHash)
+	}
+	if bal, _ := sim.BalanceAt(ctx, to, nil); bal.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want 1000", bal)
+	}
+	// The genesis state must still be readable.
+	if bal, _ := sim.BalanceAt(ctx, testAddr, big.NewInt(0)); bal.Cmp(testBalance) != 0 {
+		t.Fatalf("genesis balance mismatch: have %v, want %v", bal, testBalance)
+	}
+}
+
+func TestSimulatedBackendRollback(t *testing.T) {
+	t.Parallel()
+	sim := newTestBackend()
+	defer sim.Close()
+	ctx := context.Background()
+
+	to := common.HexToAddress("0x0102")
+	tx := newTx(t, sim, &to, big.NewInt(1000), params.TxGas, nil)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("could not send tx: %v", err)
+	}
+	sim.Rollback()
+
+	if _, _, err := sim.TransactionByHash(ctx, tx.Hash()); !errors.Is(err, ethereum.NotFound) {
+		t.Fatalf("rolled back tx found: %v", err)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, testAddr); nonce != 0 {
+		t.Fatalf("pending nonce mismatch: have %d, want 0", nonce)
+	}
+	sim.Commit()
+	if block, _ := sim.BlockByNumber(ctx, nil); len(block.Transactions()) != 0 {
+		t.Fatalf("rolled back tx included in block")
+	}
+}
+
+func TestSimulatedBackendAdjustTime(t *testing.T) {
+	t.Parallel()
+	sim := newTestBackend()
+	defer sim.Close()
+	ctx := context.Background()
+
+	prev, _ := sim.HeaderByNumber(ctx, nil)
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("could not adjust time: %v", err)
+	}
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	if head.Time-prev.Time != 3600 {
+		t.Fatalf("time not adjusted: have %d, want %d", head.Time-prev.Time, 3600)
+	}
+	to := common.HexToAddress("0x0102")
+	if err := sim.SendTransaction(ctx, newTx(t, sim, &to, big.NewInt(1), params.TxGas, nil)); err != nil {
+		t.Fatalf("could not send tx: %v", err)
+	}
+	if err := sim.AdjustTime(time.Hour); err == nil {
+		t.Fatal("adjusted time with pending transactions")
+	}
+}
+
+func TestSimulatedBackendCallContract(t *testing.T) {
+	t.Parallel()
+	sim := newTestBackend()
+	defer sim.Close()
+	ctx := context.Background()
+
+	ret := deploy(t, sim, returnCode)
+	rev := deploy(t, sim, revertCode("oops"))
+
+	if code, _ := sim.CodeAt(ctx, ret, nil); !bytes.Equal(code, returnCode) {
+		t.Fatalf("code mismatch: have %x, want %x", code, returnCode)
+	}
+	want := common.LeftPadBytes([]byte{0x2a}, 32)
+	out, err := sim.CallContract(ctx, ethereum.CallMsg{From: testAddr, To: &ret}, nil)
+	if err != nil || !bytes.Equal(out, want) {
+		t.Fatalf("call mismatch: have %x (%v), want %x", out, err, want)
+	}
+	out, err = sim.PendingCallContract(ctx, ethereum.CallMsg{From: testAddr, To: &ret})
+	if err != nil || !bytes.Equal(out, want) {
+		t.Fatalf("pending call mismatch: have %x (%v), want %x", out, err, want)
+	}
+	// The contract doesn't exist before its deployment block.
+	if out, err := sim.CallContract(ctx, ethereum.CallMsg{From: testAddr, To: &ret}, big.NewInt(0)); err != nil || len(out) != 0 {
+		t.Fatalf("historical call mismatch: have %x (%v), want empty", out, err)
+	}
+	for _, call := range []func() ([]byte, error){
+		func() ([]byte, error) { return sim.CallContract(ctx, ethereum.CallMsg{From: testAddr, To: &rev}, nil) },
+		func() ([]byte, error) {
+			return sim.PendingCallContract(ctx, ethereum.CallMsg{From: testAddr, To: &rev})
+		},
+	} {
+		_, err := call()
+		if err == nil || err.Error() != "execution reverted: oops" {
+			t.Fatalf("revert error mismatch: have %v", err)
+		}
+		var rerr *revertError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("expected revert error, got %T", err)
+		}
+		if data := rerr.ErrorData(); data != hexutil.Encode(revertCode("oops")[12:]) {
+			t.Fatalf("revert data mismatch: have %v", data)
+		}
+	}
+}
+
+func TestSimulatedBackendEstimateGas(t *testing.T) {
+	t.Parallel()
+	sim := newTestBackend()
+	defer sim.Close()
+	ctx := context.Background()
+
+	to := common.HexToAddress("0x0102")
+	gas, err := sim.EstimateGas(ctx, ethereum.CallMsg{From: testAddr, To: &to, Value: big.NewInt(1)})
+	if err != nil || gas != params.TxGas {
+		t.Fatalf("transfer estimate mismatch: have %d (%v), want %d", gas, err, params.TxGas)
+	}
+	ret := deploy(t, sim, returnCode)
+	gas, err = sim.EstimateGas(ctx, ethereum.CallMsg{From: testAddr, To: &ret})
+	if err != nil || gas <= params.TxGas {
+		t.Fatalf("call estimate mismatch: have %d (%v)", gas, err)
+	}
+	rev := deploy(t, sim, revertCode("oops"))
+	if _, err := sim.EstimateGas(ctx, ethereum.CallMsg{From: testAddr, To: &rev}); err == nil || err.Error() != "execution reverted: oops" {
+		t.Fatalf("revert estimate error mismatch: have %v", err)
+	}
+}
+
+func TestSimulatedBackendLogs(t *testing.T) {
+	t.Parallel()
+	sim := newTestBackend()
+	defer sim.Close()
+	ctx := context.Background()
+
+	addr := deploy(t, sim, logCode)
+	query := ethereum.FilterQuery{Addresses: []common.Address{addr}, Topics: [][]common.Hash{{logTopic}}}
+
+	logs := make(chan types.Log, 1)
+	sub, err := sim.SubscribeFilterLogs(ctx, query, logs)
+	if err != nil {
+		t.Fatalf("could not subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	tx := newTx(t, sim, &addr, new(big.Int), 100000, nil)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("could not send tx: %v", err)
+	}
+	hash := sim.Commit()
+
+	select {
+	case log := <-logs:
+		if log.TxHash != tx.Hash() || log.BlockHash != hash {
+			t.Fatalf("subscribed log mismatch: tx %x, block %x", log.TxHash, log.BlockHash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("log not delivered")
+	}
+	found, err := sim.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(0), Addresses: query.Addresses})
+	if err != nil {
+		t.Fatalf("could not filter logs: %v", err)
+	}
+	if len(found) != 1 || found[0].TxHash != tx.Hash() || found[0].Topics[0] != logTopic {
+		t.Fatalf("filtered logs mismatch: %v", found)
+	}
+	found, _ = sim.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &hash, Topics: [][]common.Hash{{common.Hash{0x01}}}})
+	if len(found) != 0 {
+		t.Fatalf("unexpected logs for unrelated topic: %v", found)
+	}
+}
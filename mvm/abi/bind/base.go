@@ -24,10 +24,11 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/a1146910248/mixchain"
 	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm/abi"
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/types"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/event"
 )
 
@@ -24,12 +24,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/a1146910248/mixchain"
 	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm/abi"
+	"github.com/a1146910248/mixchain/mvm/abi/bind"
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/common/hexutil"
 	"github.com/a1146910248/mixchain/mvm/types"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stretchr/testify/assert"
 )
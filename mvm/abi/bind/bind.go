@@ -29,7 +29,7 @@ import (
 	"text/template"
 	"unicode"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/a1146910248/mixchain/mvm/abi"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -287,8 +287,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -343,8 +343,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -389,8 +389,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -446,8 +446,8 @@ var bindTests = []struct {
 			"math/big"
 			"reflect"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/common"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
@@ -495,8 +495,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -562,8 +562,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -607,8 +607,8 @@ var bindTests = []struct {
 		[]string{`6060604052609f8060106000396000f3606060405260e060020a6000350463f97a60058114601a575b005b600060605260c0604052600d60809081527f4920646f6e27742065786973740000000000000000000000000000000000000060a052602060c0908152600d60e081905281906101009060a09080838184600060046012f15050815172ffffffffffffffffffffffffffffffffffffff1916909152505060405161012081900392509050f3`},
 		[]string{`[{"constant":true,"inputs":[],"name":"String","outputs":[{"name":"","type":"string"}],"type":"function"}]`},
 		`
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/common"
 			"github.com/a1146910248/mixchain/mvm/types"
 		`,
@@ -646,8 +646,8 @@ var bindTests = []struct {
 		[]string{`6080604052348015600f57600080fd5b5060888061001e6000396000f3fe6080604052348015600f57600080fd5b506004361060285760003560e01c8063d5f6622514602d575b600080fd5b6033604c565b6040805192835260208301919091528051918290030190f35b600a809156fea264697066735822beefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeefbeef64736f6c6343decafe0033`},
 		[]string{`[{"inputs":[],"name":"Struct","outputs":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256","name":"b","type":"uint256"}],"stateMutability":"pure","type":"function"}]`},
 		`
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/common"
 			"github.com/a1146910248/mixchain/mvm/types"
 		`,
@@ -694,8 +694,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -743,8 +743,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/common"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
@@ -819,8 +819,8 @@ var bindTests = []struct {
 			"fmt"
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -912,8 +912,8 @@ var bindTests = []struct {
 			"math/big"
 			"time"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/common"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
@@ -1103,8 +1103,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -1238,8 +1238,8 @@ var bindTests = []struct {
 			"math/big"
 			"reflect"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -1380,8 +1380,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -1446,8 +1446,8 @@ var bindTests = []struct {
 		"math/big"
 		"time"
 
-		"github.com/ethereum/go-ethereum/accounts/abi/bind"
-		"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+		"github.com/a1146910248/mixchain/mvm/abi/bind"
+		"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 		"github.com/a1146910248/mixchain/mvm/types"
 		"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -1468,15 +1468,16 @@ var bindTests = []struct {
 
 		resCh, stopCh := make(chan uint64), make(chan struct{})
 
-		go func() {
-			barSink := make(chan *OverloadBar)
-			sub, _ := contract.WatchBar(nil, barSink)
-			defer sub.Unsubscribe()
+		// Subscribe before emitting any event, so that none can be missed.
+		barSink := make(chan *OverloadBar)
+		sub, _ := contract.WatchBar(nil, barSink)
+		defer sub.Unsubscribe()
 
-			bar0Sink := make(chan *OverloadBar0)
-			sub0, _ := contract.WatchBar0(nil, bar0Sink)
-			defer sub0.Unsubscribe()
+		bar0Sink := make(chan *OverloadBar0)
+		sub0, _ := contract.WatchBar0(nil, bar0Sink)
+		defer sub0.Unsubscribe()
 
+		go func() {
 			for {
 				select {
 				case ev := <-barSink:
@@ -1534,8 +1535,8 @@ var bindTests = []struct {
 		`
 		"math/big"
 
-		"github.com/ethereum/go-ethereum/accounts/abi/bind"
-		"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+		"github.com/a1146910248/mixchain/mvm/abi/bind"
+		"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 		"github.com/a1146910248/mixchain/crypto"
 		"github.com/a1146910248/mixchain/mvm/types"
 		`,
@@ -1597,8 +1598,8 @@ var bindTests = []struct {
 		`
 		"math/big"
 
-		"github.com/ethereum/go-ethereum/accounts/abi/bind"
-		"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+		"github.com/a1146910248/mixchain/mvm/abi/bind"
+		"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 		"github.com/a1146910248/mixchain/crypto"
 		"github.com/a1146910248/mixchain/mvm/types"
         `,
@@ -1659,8 +1660,8 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 		`,
@@ -1720,8 +1721,8 @@ var bindTests = []struct {
 			"bytes"
 			"math/big"
 	
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
 	   `,
@@ -1808,17 +1809,16 @@ var bindTests = []struct {
 		`
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
-			"github.com/ethereum/go-ethereum/eth/ethconfig"
 	   `,
 		`
 			var (
 				key, _  = crypto.GenerateKey()
 				user, _ = bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
-				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, ethconfig.Defaults.Miner.GasCeil)
+				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, 30000000)
 			)
 			defer sim.Close()
 
@@ -1879,17 +1879,16 @@ var bindTests = []struct {
 			"context"
 			"math/big"
 	
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
-			"github.com/ethereum/go-ethereum/eth/ethconfig"
 	   `,
 		`
 			var (
 				key, _  = crypto.GenerateKey()
 				user, _ = bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
-				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, ethconfig.Defaults.Miner.GasCeil)
+				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, 30000000)
 			)
 			defer sim.Close()
 	
@@ -1932,17 +1931,16 @@ var bindTests = []struct {
 			"context"
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
-			"github.com/ethereum/go-ethereum/eth/ethconfig"
 		`,
 		tester: `
 			var (
 				key, _  = crypto.GenerateKey()
 				user, _ = bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
-				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, ethconfig.Defaults.Miner.GasCeil)
+				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, 30000000)
 			)
 			defer sim.Close()
 
@@ -1981,17 +1979,16 @@ var bindTests = []struct {
 			"context"
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
-			"github.com/ethereum/go-ethereum/eth/ethconfig"
 		`,
 		tester: `
 			var (
 				key, _  = crypto.GenerateKey()
 				user, _ = bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
-				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, ethconfig.Defaults.Miner.GasCeil)
+				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, 30000000)
 			)
 			defer sim.Close()
 
@@ -2022,17 +2019,16 @@ var bindTests = []struct {
 			"context"
 			"math/big"
 
-			"github.com/ethereum/go-ethereum/accounts/abi/bind"
-			"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+			"github.com/a1146910248/mixchain/mvm/abi/bind"
+			"github.com/a1146910248/mixchain/mvm/abi/bind/backends"
 			"github.com/a1146910248/mixchain/mvm/types"
 			"github.com/a1146910248/mixchain/crypto"
-			"github.com/ethereum/go-ethereum/eth/ethconfig"
 		`,
 		tester: `
 			var (
 				key, _  = crypto.GenerateKey()
 				user, _ = bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
-				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, ethconfig.Defaults.Miner.GasCeil)
+				sim     = backends.NewSimulatedBackend(types.GenesisAlloc{user.From: {Balance: big.NewInt(1000000000000000000)}}, 30000000)
 			)
 			_, tx, _, err := DeployRangeKeyword(user, sim)
 			if err != nil {
@@ -2121,14 +2117,14 @@ func TestGolangBindings(t *testing.T) {
 			}
 		})
 	}
-	// Convert the package to go modules and use the current source for go-ethereum
+	// Convert the package to go modules and use the current source for mixchain
 	moder := exec.Command(gocmd, "mod", "init", "bindtest")
 	moder.Dir = pkg
 	if out, err := moder.CombinedOutput(); err != nil {
 		t.Fatalf("failed to convert binding test to modules: %v\n%s", err, out)
 	}
 	pwd, _ := os.Getwd()
-	replacer := exec.Command(gocmd, "mod", "edit", "-x", "-require", "github.com/ethereum/go-ethereum@v0.0.0", "-replace", "github.com/ethereum/go-ethereum="+filepath.Join(pwd, "..", "..", "..")) // Repo root
+	replacer := exec.Command(gocmd, "mod", "edit", "-x", "-require", "github.com/a1146910248/mixchain@v0.0.0", "-replace", "github.com/a1146910248/mixchain="+filepath.Join(pwd, "..", "..", "..")) // Repo root
 	replacer.Dir = pkg
 	if out, err := replacer.CombinedOutput(); err != nil {
 		t.Fatalf("failed to replace binding test dependency to current source tree: %v\n%s", err, out)
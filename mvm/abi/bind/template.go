@@ -16,7 +16,7 @@
 
 package bind
 
-import "github.com/ethereum/go-ethereum/accounts/abi"
+import "github.com/a1146910248/mixchain/mvm/abi"
 
 // tmplData is the data structure required to fill the binding template.
 type tmplData struct {
@@ -91,9 +91,9 @@ import (
 	"strings"
 	"errors"
 
-	ethereum "github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethereum "github.com/a1146910248/mixchain"
+	"github.com/a1146910248/mixchain/mvm/abi"
+	"github.com/a1146910248/mixchain/mvm/abi/bind"
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/types"
 	"github.com/ethereum/go-ethereum/event"
@@ -21,8 +21,9 @@ import (
 	"errors"
 	"time"
 
+	"github.com/a1146910248/mixchain"
+	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/types"
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -0,0 +1,284 @@
+package mvm
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BlockChain is a minimal chain manager on top of a key-value database. It
+// tracks a single canonical chain starting at the genesis block: blocks are
+// only accepted if they extend the current head, there is no reorg support.
+//
+// Headers, bodies, receipts and the transaction lookup index are persisted
+// with the rawdb accessors, the state of every block is committed to the same
+// database, so the chain can be reopened from disk.
+type BlockChain struct {
+	chainConfig *params.ChainConfig // Chain & network configuration
+	db          ethdb.KeyValueStore // Low level persistent database to store final content in
+	stateCache  state.Database      // State database to reuse between imports (contains state cache)
+	vmConfig    vm.Config
+	processor   Processor
+
+	genesisBlock *types.Block
+	currentBlock atomic.Pointer[types.Header] // Current head of the chain
+
+	chainmu sync.Mutex // blockchain insertion lock
+}
+
+// NewBlockChain returns a fully initialised block chain using information
+// available in the database. If the database holds no chain yet, the genesis
+// block is built from the given spec and committed first; with an existing
+// chain, genesis may be nil or must match the stored genesis block.
+func NewBlockChain(db ethdb.KeyValueStore, genesis *Genesis, vmConfig vm.Config) (*BlockChain, error) {
+	chainConfig, genesisHash, err := SetupGenesisBlock(db, genesis)
+	if err != nil {
+		return nil, err
+	}
+	bc := &BlockChain{
+		chainConfig: chainConfig,
+		db:          db,
+		stateCache:  state.NewDatabase(db),
+		vmConfig:    vmConfig,
+	}
+	bc.processor = NewStateProcessor(chainConfig, bc)
+
+	bc.genesisBlock = bc.GetBlock(genesisHash, 0)
+	if bc.genesisBlock == nil {
+		return nil, ErrNoGenesis
+	}
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		log.Warn("Head block missing, resetting to genesis")
+		head = bc.genesisBlock.Header()
+	}
+	// Make sure the state associated with the head block is available.
+	if _, err := state.New(head.Root, bc.stateCache); err != nil {
+		return nil, fmt.Errorf("missing state of head block %d [%x]: %w", head.Number, head.Hash(), err)
+	}
+	bc.currentBlock.Store(head)
+	log.Info("Loaded most recent local block", "number", head.Number, "hash", head.Hash())
+	return bc, nil
+}
+
+// Config retrieves the chain's fork configuration.
+func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
+
+// Genesis retrieves the chain's genesis block.
+func (bc *BlockChain) Genesis() *types.Block { return bc.genesisBlock }
+
+// CurrentBlock retrieves the current head block of the canonical chain.
+func (bc *BlockChain) CurrentBlock() *types.Header {
+	return bc.currentBlock.Load()
+}
+
+// CurrentHeader retrieves the current head header of the canonical chain. It
+// is the same as CurrentBlock, as only full blocks are imported.
+func (bc *BlockChain) CurrentHeader() *types.Header {
+	return bc.currentBlock.Load()
+}
+
+// GetHeader retrieves a block header from the database by hash and number.
+func (bc *BlockChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(bc.db, hash, number)
+}
+
+// GetHeaderByHash retrieves a block header from the database by hash.
+func (bc *BlockChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return bc.GetHeader(hash, *number)
+}
+
+// GetHeaderByNumber retrieves a canonical block header from the database by number.
+func (bc *BlockChain) GetHeaderByNumber(number uint64) *types.Header {
+	hash := rawdb.ReadCanonicalHash(bc.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return bc.GetHeader(hash, number)
+}
+
+// GetCanonicalHash returns the canonical hash for a given block number.
+func (bc *BlockChain) GetCanonicalHash(number uint64) common.Hash {
+	return rawdb.ReadCanonicalHash(bc.db, number)
+}
+
+// HasBlock checks if a block is fully present in the database or not.
+func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
+	return rawdb.HasBody(bc.db, hash, number)
+}
+
+// GetBlock retrieves a block from the database by hash and number.
+func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return rawdb.ReadBlock(bc.db, hash, number)
+}
+
+// GetBlockByHash retrieves a block from the database by hash.
+func (bc *BlockChain) GetBlockByHash(hash common.Hash) *types.Block {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return bc.GetBlock(hash, *number)
+}
+
+// GetBlockByNumber retrieves a canonical block from the database by number.
+func (bc *BlockChain) GetBlockByNumber(number uint64) *types.Block {
+	hash := rawdb.ReadCanonicalHash(bc.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return bc.GetBlock(hash, number)
+}
+
+// GetReceiptsByHash retrieves the receipts for all transactions in a given
+// block, with their derived fields filled in.
+func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	header := bc.GetHeaderByHash(hash)
+	if header == nil {
+		return nil
+	}
+	return rawdb.ReadReceipts(bc.db, hash, header.Number.Uint64(), header.Time, bc.chainConfig)
+}
+
+// GetTransaction retrieves a canonical transaction along with the hash and
+// number of the block it was included in and its index within the block.
+func (bc *BlockChain) GetTransaction(hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
+	return rawdb.ReadTransaction(bc.db, hash)
+}
+
+// GetReceipt retrieves the receipt of a canonical transaction along with the
+// hash and number of the block it was included in and its index within the
+// block.
+func (bc *BlockChain) GetReceipt(hash common.Hash) (*types.Receipt, common.Hash, uint64, uint64) {
+	return rawdb.ReadReceipt(bc.db, hash, bc.chainConfig)
+}
+
+// State returns a new mutable state based on the current HEAD block.
+func (bc *BlockChain) State() (*state.StateDB, error) {
+	return bc.StateAt(bc.CurrentBlock().Root)
+}
+
+// StateAt returns a new mutable state based on a particular point in time.
+// Only the state of the head block can be committed.
+func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, bc.stateCache)
+}
+
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
+}
+
+// InsertChain attempts to insert the given batch of blocks in to the canonical
+// chain. It returns the index of the failing block when an error occurs.
+func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
+	for i, block := range chain {
+		if err := bc.InsertBlock(block); err != nil {
+			return i, err
+		}
+	}
+	return 0, nil
+}
+
+// InsertBlock validates and executes a block on top of the current head and
+// makes it the new head. The block must be the direct child of the head.
+func (bc *BlockChain) InsertBlock(block *types.Block) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if bc.HasBlock(block.Hash(), block.NumberU64()) {
+		return ErrKnownBlock
+	}
+	if block.NumberU64() == 0 {
+		return ErrUnknownAncestor
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return ErrUnknownAncestor
+	}
+	if parent.Hash() != bc.CurrentBlock().Hash() {
+		return ErrSideChain
+	}
+	if err := ValidateHeader(bc.chainConfig, parent, block.Header()); err != nil {
+		return err
+	}
+	if err := ValidateBody(block); err != nil {
+		return err
+	}
+	statedb, err := state.New(parent.Root, bc.stateCache)
+	if err != nil {
+		return err
+	}
+	res, err := bc.processor.Process(block, statedb, bc.vmConfig)
+	if err != nil {
+		return err
+	}
+	if err := ValidateState(block.Header(), res); err != nil {
+		return err
+	}
+	return bc.writeBlockAndSetHead(block, res.Receipts, statedb)
+}
+
+// WriteBlockAndSetHead writes a block that was built locally (e.g. by the
+// miner) together with its receipts and post-state, and makes it the new head.
+// The block is not executed again, statedb must hold its post-state and be
+// opened on the state of the current head.
+func (bc *BlockChain) WriteBlockAndSetHead(block *types.Block, receipts types.Receipts, statedb *state.StateDB) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if bc.HasBlock(block.Hash(), block.NumberU64()) {
+		return ErrKnownBlock
+	}
+	if block.ParentHash() != bc.CurrentBlock().Hash() {
+		return ErrSideChain
+	}
+	return bc.writeBlockAndSetHead(block, receipts, statedb)
+}
+
+// writeBlockAndSetHead commits the post-state of the block, persists the block
+// with its receipts and updates the canonical head. The caller must hold chainmu.
+//
+// Committing the state moves the head of the state history, so the root is
+// verified before anything is written, and the state head is rolled back to
+// the parent if the block itself can't be stored. Otherwise the parent state
+// would turn read-only and no child block could be written anymore.
+func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts types.Receipts, statedb *state.StateDB) error {
+	deleteEmpty := bc.chainConfig.IsEIP158(block.Number())
+	if root := statedb.IntermediateRoot(deleteEmpty); root != block.Root() {
+		return fmt.Errorf("invalid merkle root (remote: %x local: %x)", block.Root(), root)
+	}
+	parent := bc.CurrentBlock()
+	if _, err := statedb.Commit(deleteEmpty); err != nil {
+		return err
+	}
+	batch := bc.db.NewBatch()
+	rawdb.WriteBlock(batch, block)
+	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
+	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	rawdb.WriteHeadHeaderHash(batch, block.Hash())
+	rawdb.WriteHeadBlockHash(batch, block.Hash())
+	if err := batch.Write(); err != nil {
+		if rerr := state.Rollback(bc.stateCache, parent.Root); rerr != nil {
+			log.Error("Failed to roll back state", "root", parent.Root, "err", rerr)
+		}
+		return fmt.Errorf("failed to write block: %w", err)
+	}
+	bc.currentBlock.Store(block.Header())
+	log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()), "gas", block.GasUsed())
+	return nil
+}
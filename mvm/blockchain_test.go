@@ -0,0 +1,300 @@
+package mvm
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip1559"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip4844"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/trie"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+)
+
+var testStorageKey = common.HexToHash("0x01")
+
+func newTestGenesis() *Genesis {
+	return &Genesis{
+		Config:   params.MergedTestChainConfig,
+		GasLimit: 30_000_000,
+		Alloc: types.GenesisAlloc{
+			testAddr:   {Balance: big.NewInt(params.InitialBaseFee * 10_000_000)},
+			testLogger: {Code: loggerCode, Nonce: 1, Storage: map[common.Hash]common.Hash{testStorageKey: common.HexToHash("0x02")}},
+		},
+	}
+}
+
+// makeBlock executes txs on top of the chain head and seals a valid child
+// block with the results, without inserting it.
+func makeBlock(t *testing.T, bc *BlockChain, txs types.Transactions) *types.Block {
+	t.Helper()
+	var (
+		config        = bc.Config()
+		parent        = bc.CurrentBlock()
+		excessBlobGas = eip4844.CalcExcessBlobGas(*parent.ExcessBlobGas, *parent.BlobGasUsed)
+		blobGasUsed   = uint64(0)
+		header        = &types.Header{
+			ParentHash:       parent.Hash(),
+			Number:           new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:         parent.GasLimit,
+			Time:             parent.Time + 10,
+			Coinbase:         testCoinbase,
+			Difficulty:       new(big.Int),
+			BaseFee:          eip1559.CalcBaseFee(config, parent),
+			ExcessBlobGas:    &excessBlobGas,
+			BlobGasUsed:      &blobGasUsed,
+			ParentBeaconRoot: new(common.Hash),
+		}
+		gp       = new(GasPool).AddGas(header.GasLimit)
+		receipts types.Receipts
+	)
+	statedb, err := bc.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	for i, tx := range txs {
+		statedb.SetTxContext(tx.Hash(), i)
+		receipt, err := ApplyTransaction(config, bc, gp, statedb, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to apply tx %d: %v", i, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	header.Root = statedb.IntermediateRoot(config.IsEIP158(header.Number))
+	return types.NewBlockWithWithdrawals(header, txs, nil, receipts, []*types.Withdrawal{}, trie.NewEmpty())
+}
+
+func TestGenesisCommit(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = newTestGenesis()
+		block   = genesis.MustCommit(db)
+	)
+	if block.Hash() != genesis.ToBlock().Hash() {
+		t.Fatalf("committed genesis hash mismatch: have %x, want %x", block.Hash(), genesis.ToBlock().Hash())
+	}
+	if rawdb.ReadCanonicalHash(db, 0) != block.Hash() || rawdb.ReadHeadBlockHash(db) != block.Hash() {
+		t.Fatalf("genesis not stored as canonical head")
+	}
+	if block.BaseFee().Uint64() != params.InitialBaseFee || *block.ExcessBlobGas() != 0 || block.Withdrawals() == nil {
+		t.Errorf("fork specific genesis fields not set")
+	}
+	bc, err := NewBlockChain(db, nil, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open chain: %v", err)
+	}
+	if bc.Config().ChainID.Cmp(genesis.Config.ChainID) != 0 {
+		t.Errorf("stored chain config mismatch")
+	}
+	statedb, err := bc.State()
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	if have := statedb.GetBalance(testAddr).ToBig(); have.Cmp(genesis.Alloc[testAddr].Balance) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", have, genesis.Alloc[testAddr].Balance)
+	}
+	if statedb.GetNonce(testLogger) != 1 || string(statedb.GetCode(testLogger)) != string(loggerCode) {
+		t.Errorf("contract account mismatch")
+	}
+	if have := statedb.GetState(testLogger, testStorageKey); have != common.HexToHash("0x02") {
+		t.Errorf("storage mismatch: have %x", have)
+	}
+}
+
+func TestSetupGenesisBlock(t *testing.T) {
+	if _, _, err := SetupGenesisBlock(rawdb.NewMemoryDatabase(), nil); !errors.Is(err, ErrNoGenesis) {
+		t.Errorf("empty database: have %v, want %v", err, ErrNoGenesis)
+	}
+	if _, _, err := SetupGenesisBlock(rawdb.NewMemoryDatabase(), &Genesis{}); err == nil {
+		t.Errorf("genesis without config accepted")
+	}
+	db := rawdb.NewMemoryDatabase()
+	_, hash, err := SetupGenesisBlock(db, newTestGenesis())
+	if err != nil {
+		t.Fatalf("failed to setup genesis: %v", err)
+	}
+	// The same genesis can be set up again, a different one is rejected.
+	if _, stored, err := SetupGenesisBlock(db, newTestGenesis()); err != nil || stored != hash {
+		t.Errorf("compatible genesis: have %x, %v, want %x", stored, err, hash)
+	}
+	other := newTestGenesis()
+	other.GasLimit++
+	var mismatch *GenesisMismatchError
+	if _, _, err := SetupGenesisBlock(db, other); !errors.As(err, &mismatch) || mismatch.Stored != hash {
+		t.Errorf("incompatible genesis: have %v, want mismatch error", err)
+	}
+}
+
+func TestBlockChainInsert(t *testing.T) {
+	bc, err := NewBlockChain(rawdb.NewMemoryDatabase(), newTestGenesis(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	to := common.HexToAddress("0xdead")
+	blocks := make(types.Blocks, 3)
+	for i := range blocks {
+		txs := types.Transactions{
+			signDynamicTx(testKey, uint64(2*i), &to, params.TxGas, nil),
+			signDynamicTx(testKey, uint64(2*i+1), &testLogger, 50000, nil),
+		}
+		blocks[i] = makeBlock(t, bc, txs)
+		if err := bc.InsertBlock(blocks[i]); err != nil {
+			t.Fatalf("failed to insert block %d: %v", i+1, err)
+		}
+	}
+	if head := bc.CurrentBlock(); head.Hash() != blocks[2].Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.Number, blocks[2].Number())
+	}
+	for _, block := range blocks {
+		number, hash := block.NumberU64(), block.Hash()
+		if bc.GetCanonicalHash(number) != hash || bc.GetHeaderByNumber(number).Hash() != hash {
+			t.Errorf("block %d: canonical lookup mismatch", number)
+		}
+		if b := bc.GetBlockByHash(hash); b == nil || len(b.Transactions()) != 2 || bc.GetBlockByNumber(number).Hash() != hash {
+			t.Errorf("block %d: block lookup mismatch", number)
+		}
+		receipts := bc.GetReceiptsByHash(hash)
+		if len(receipts) != 2 || len(receipts[1].Logs) != 1 {
+			t.Fatalf("block %d: receipts mismatch", number)
+		}
+		for i, receipt := range receipts {
+			tx := block.Transactions()[i]
+			if receipt.TxHash != tx.Hash() || receipt.BlockHash != hash || receipt.BlockNumber.Uint64() != number || receipt.TransactionIndex != uint(i) {
+				t.Errorf("block %d receipt %d: derived fields mismatch", number, i)
+			}
+			if receipt.EffectiveGasPrice == nil || (len(receipt.Logs) > 0 && receipt.Logs[0].TxHash != tx.Hash()) {
+				t.Errorf("block %d receipt %d: derived fields mismatch", number, i)
+			}
+			ltx, lhash, lnumber, lindex := bc.GetTransaction(tx.Hash())
+			if ltx == nil || ltx.Hash() != tx.Hash() || lhash != hash || lnumber != number || lindex != uint64(i) {
+				t.Errorf("block %d tx %d: lookup mismatch", number, i)
+			}
+		}
+	}
+	if bc.GetBlockByNumber(4) != nil || bc.GetHeaderByHash(common.Hash{1}) != nil {
+		t.Errorf("unknown block found")
+	}
+	statedb, _ := bc.State()
+	if have := statedb.GetNonce(testAddr); have != 6 {
+		t.Errorf("nonce mismatch: have %d, want 6", have)
+	}
+	if have := statedb.GetBalance(to).Uint64(); have != 3 {
+		t.Errorf("balance mismatch: have %d, want 3", have)
+	}
+}
+
+func TestBlockChainInsertErrors(t *testing.T) {
+	bc, err := NewBlockChain(rawdb.NewMemoryDatabase(), newTestGenesis(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	to := common.HexToAddress("0xdead")
+	first := makeBlock(t, bc, types.Transactions{signDynamicTx(testKey, 0, &to, params.TxGas, nil)})
+	side := makeBlock(t, bc, types.Transactions{signDynamicTx(testKey, 0, &testLogger, 50000, nil)})
+
+	// A block with a wrong state root is rejected and leaves the head untouched.
+	header := first.Header()
+	header.Root = common.Hash{1}
+	if err := bc.InsertBlock(first.WithSeal(header)); err == nil {
+		t.Errorf("block with invalid root accepted")
+	}
+	if n, err := bc.InsertChain(types.Blocks{first}); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	if err := bc.InsertBlock(first); !errors.Is(err, ErrKnownBlock) {
+		t.Errorf("known block: have %v, want %v", err, ErrKnownBlock)
+	}
+	if err := bc.InsertBlock(side); !errors.Is(err, ErrSideChain) {
+		t.Errorf("side chain: have %v, want %v", err, ErrSideChain)
+	}
+	header = first.Header()
+	header.ParentHash = common.Hash{1}
+	if err := bc.InsertBlock(types.NewBlockWithHeader(header)); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("unknown ancestor: have %v, want %v", err, ErrUnknownAncestor)
+	}
+	// Header validation: timestamps must increase.
+	next := makeBlock(t, bc, nil)
+	header = next.Header()
+	header.Time = first.Time()
+	if err := bc.InsertBlock(next.WithSeal(header)); err == nil {
+		t.Errorf("block with old timestamp accepted")
+	}
+	if head := bc.CurrentBlock(); head.Hash() != first.Hash() {
+		t.Errorf("head moved by invalid blocks: have %d", head.Number)
+	}
+}
+
+func TestWriteBlockInvalidRoot(t *testing.T) {
+	bc, err := NewBlockChain(rawdb.NewMemoryDatabase(), newTestGenesis(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	to := common.HexToAddress("0xdead")
+	block := makeBlock(t, bc, types.Transactions{signDynamicTx(testKey, 0, &to, params.TxGas, nil)})
+
+	// A locally built block with a wrong root must not touch the database.
+	statedb, _ := bc.State()
+	res, err := bc.processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	header := block.Header()
+	header.Root = common.Hash{1}
+	if err := bc.WriteBlockAndSetHead(block.WithSeal(header), res.Receipts, statedb); err == nil {
+		t.Fatalf("block with invalid root written")
+	}
+	if head := bc.CurrentBlock(); head.Hash() != bc.Genesis().Hash() {
+		t.Fatalf("head moved by invalid block: have %d", head.Number)
+	}
+	// The head state is still writable, so the valid block can be inserted.
+	if err := bc.InsertBlock(block); err != nil {
+		t.Fatalf("failed to insert block after invalid write: %v", err)
+	}
+	statedb, _ = bc.State()
+	if have := statedb.GetBalance(to).Uint64(); have != 1 {
+		t.Errorf("balance mismatch: have %d, want 1", have)
+	}
+}
+
+func TestBlockChainReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chaindata")
+	db, err := rawdb.NewLevelDBDatabase(path, 16, 16, "", false)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	bc, err := NewBlockChain(db, newTestGenesis(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	to := common.HexToAddress("0xdead")
+	block := makeBlock(t, bc, types.Transactions{signDynamicTx(testKey, 0, &to, params.TxGas, nil)})
+	if err := bc.InsertBlock(block); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	db.Close()
+
+	if db, err = rawdb.NewLevelDBDatabase(path, 16, 16, "", false); err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	if bc, err = NewBlockChain(db, nil, vm.Config{}); err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	if head := bc.CurrentBlock(); head.Hash() != block.Hash() {
+		t.Fatalf("head mismatch after reopen: have %d, want %d", head.Number, block.Number())
+	}
+	// The chain continues on top of the persisted head.
+	next := makeBlock(t, bc, types.Transactions{signDynamicTx(testKey, 1, &to, params.TxGas, nil)})
+	if err := bc.InsertBlock(next); err != nil {
+		t.Fatalf("failed to insert block after reopen: %v", err)
+	}
+	statedb, _ := bc.State()
+	if have := statedb.GetBalance(to).Uint64(); have != 2 {
+		t.Errorf("balance mismatch: have %d, want 2", have)
+	}
+}
@@ -0,0 +1,68 @@
+package mvm
+
+import (
+	"sync"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/types"
+)
+
+// MemoryHeaderStore is an in-memory HeaderReader, mainly used by tests and
+// tools which have no persistent chain.
+type MemoryHeaderStore struct {
+	headers map[common.Hash]*types.Header
+	numbers map[uint64]common.Hash // the most recently added header of each number
+	lock    sync.RWMutex
+}
+
+// NewMemoryHeaderStore returns an empty header store.
+func NewMemoryHeaderStore() *MemoryHeaderStore {
+	return &MemoryHeaderStore{
+		headers: make(map[common.Hash]*types.Header),
+		numbers: make(map[uint64]common.Hash),
+	}
+}
+
+// AddHeader stores the header and makes it the header returned by
+// GetHeaderByNumber for its number.
+func (s *MemoryHeaderStore) AddHeader(header *types.Header) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hash := header.Hash()
+	s.headers[hash] = types.CopyHeader(header)
+	s.numbers[header.Number.Uint64()] = hash
+}
+
+// GetHeader returns the header with the given hash and number, or nil if unknown.
+func (s *MemoryHeaderStore) GetHeader(hash common.Hash, number uint64) *types.Header {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	header := s.headers[hash]
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// GetHeaderByHash returns the header with the given hash, or nil if unknown.
+func (s *MemoryHeaderStore) GetHeaderByHash(hash common.Hash) *types.Header {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.headers[hash]
+}
+
+// GetHeaderByNumber returns the most recently added header with the given
+// number, or nil if unknown.
+func (s *MemoryHeaderStore) GetHeaderByNumber(number uint64) *types.Header {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	hash, ok := s.numbers[number]
+	if !ok {
+		return nil
+	}
+	return s.headers[hash]
+}
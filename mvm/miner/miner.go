@@ -0,0 +1,195 @@
+// Package miner assembles pending transactions into blocks.
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip1559"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip4844"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/trie"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Config is the configuration parameters of block building.
+type Config struct {
+	Coinbase  common.Address // Address receiving the transaction tips
+	ExtraData []byte         // Extra data of the built blocks
+	GasCeil   uint64         // Target gas ceiling, zero keeps the gas limit of the parent
+}
+
+// Miner builds blocks on top of a given parent and state.
+type Miner struct {
+	config *params.ChainConfig
+	chain  mvm.HeaderReader
+	cfg    Config
+}
+
+// New creates a block builder. The chain is used to resolve BLOCKHASH and may
+// be nil.
+func New(config *params.ChainConfig, chain mvm.HeaderReader, cfg Config) *Miner {
+	return &Miner{
+		config: config,
+		chain:  chain,
+		cfg:    cfg,
+	}
+}
+
+// Result is a built block together with the receipts of its transactions.
+type Result struct {
+	Block    *types.Block
+	Receipts types.Receipts
+}
+
+// BuildBlock assembles a block on top of parent. The pending transactions are
+// grouped by sender and executed by effective tip, honouring the nonce order of
+// each sender, until the block gas limit or blob gas limit is reached.
+// Transactions which fail to apply are skipped together with the later ones of
+// the same sender.
+//
+// The statedb must hold the state of parent and contains the post-state of the
+// built block afterwards. It is not committed.
+func (m *Miner) BuildBlock(parent *types.Header, statedb *state.StateDB, timestamp uint64, pending map[common.Address][]*types.Transaction) (*Result, error) {
+	header, err := m.prepare(parent, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if header.ParentBeaconRoot != nil {
+		vmenv := vm.NewEVM(mvm.NewEVMBlockContext(header, m.chain), vm.TxContext{}, statedb, m.config, vm.Config{})
+		mvm.ProcessBeaconBlockRoot(*header.ParentBeaconRoot, vmenv, statedb)
+	}
+	// Sort a copy of the pending transactions, the ordering consumes the lists.
+	txs := make(map[common.Address][]*types.Transaction, len(pending))
+	for from, list := range pending {
+		list = append([]*types.Transaction(nil), list...)
+		sort.Sort(types.TxByNonce(list))
+		txs[from] = list
+	}
+	var (
+		gasPool     = new(mvm.GasPool).AddGas(header.GasLimit)
+		blobGasUsed uint64
+		included    types.Transactions
+		receipts    types.Receipts
+		ordered     = newTransactionsByPriceAndNonce(txs, header.BaseFee)
+	)
+	for !ordered.Empty() {
+		// If we don't have enough gas for any further transactions then we're done.
+		if gasPool.Gas() < params.TxGas {
+			break
+		}
+		tx, from := ordered.Peek()
+
+		// If we don't have enough space for the next transaction, skip the account.
+		if gasPool.Gas() < tx.Gas() {
+			log.Trace("Not enough gas left for transaction", "hash", tx.Hash(), "left", gasPool.Gas(), "needed", tx.Gas())
+			ordered.Pop()
+			continue
+		}
+		blobGas := uint64(len(tx.BlobHashes()) * params.BlobTxBlobGasPerBlob)
+		if blobGasUsed+blobGas > params.MaxBlobGasPerBlock {
+			log.Trace("Not enough blob gas left for transaction", "hash", tx.Hash(), "left", params.MaxBlobGasPerBlock-blobGasUsed, "needed", blobGas)
+			ordered.Pop()
+			continue
+		}
+		var (
+			snap = statedb.Snapshot()
+			gp   = gasPool.Gas()
+		)
+		statedb.SetTxContext(tx.Hash(), len(included))
+		receipt, err := mvm.ApplyTransaction(m.config, m.chain, gasPool, statedb, header, tx, &header.GasUsed, vm.Config{})
+		switch {
+		case err == nil:
+			// Everything ok, collect the logs and shift in the next transaction from the same account
+			included = append(included, tx)
+			receipts = append(receipts, receipt)
+			blobGasUsed += blobGas
+			ordered.Shift()
+
+		case errors.Is(err, mvm.ErrNonceTooLow):
+			// New head notification data race between the transaction pool and miner, shift
+			statedb.RevertToSnapshot(snap)
+			gasPool.SetGas(gp)
+			log.Trace("Skipping transaction with low nonce", "hash", tx.Hash(), "sender", from, "nonce", tx.Nonce())
+			ordered.Shift()
+
+		default:
+			// Transaction is regarded as invalid, drop all consecutive transactions from
+			// the same sender because of `nonce-too-high` clause.
+			statedb.RevertToSnapshot(snap)
+			gasPool.SetGas(gp)
+			log.Debug("Transaction failed, account skipped", "hash", tx.Hash(), "sender", from, "err", err)
+			ordered.Pop()
+		}
+	}
+	if header.ExcessBlobGas != nil {
+		header.BlobGasUsed = &blobGasUsed
+	}
+	header.Root = statedb.IntermediateRoot(m.config.IsEIP158(header.Number))
+
+	var block *types.Block
+	if m.config.IsShanghai(header.Number, header.Time) {
+		block = types.NewBlockWithWithdrawals(header, included, nil, receipts, []*types.Withdrawal{}, trie.NewEmpty())
+	} else {
+		block = types.NewBlock(header, included, nil, receipts, trie.NewEmpty())
+	}
+	// The receipts were created before the header was complete, point them and
+	// their logs to the sealed block.
+	hash := block.Hash()
+	for _, receipt := range receipts {
+		receipt.BlockHash = hash
+		for _, l := range receipt.Logs {
+			l.BlockHash = hash
+		}
+	}
+	return &Result{Block: block, Receipts: receipts}, nil
+}
+
+// prepare creates the header of the block following parent.
+func (m *Miner) prepare(parent *types.Header, timestamp uint64) (*types.Header, error) {
+	if timestamp <= parent.Time {
+		timestamp = parent.Time + 1
+	}
+	gasLimit := parent.GasLimit
+	if m.cfg.GasCeil != 0 {
+		gasLimit = mvm.CalcGasLimit(parent.GasLimit, m.cfg.GasCeil)
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   gasLimit,
+		Time:       timestamp,
+		Coinbase:   m.cfg.Coinbase,
+		Extra:      common.CopyBytes(m.cfg.ExtraData),
+		Difficulty: new(big.Int),
+	}
+	if len(header.Extra) > int(params.MaximumExtraDataSize) {
+		return nil, errors.New("extra data too long")
+	}
+	// Set baseFee and GasLimit if we are on an EIP-1559 chain
+	if m.config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(m.config, parent)
+		if !m.config.IsLondon(parent.Number) {
+			header.GasLimit = parent.GasLimit * m.config.ElasticityMultiplier()
+			if m.cfg.GasCeil != 0 {
+				header.GasLimit = mvm.CalcGasLimit(header.GasLimit, m.cfg.GasCeil)
+			}
+		}
+	}
+	// Apply EIP-4844, EIP-4788.
+	if m.config.IsCancun(header.Number, header.Time) {
+		var excessBlobGas uint64
+		if parent.ExcessBlobGas != nil && parent.BlobGasUsed != nil {
+			excessBlobGas = eip4844.CalcExcessBlobGas(*parent.ExcessBlobGas, *parent.BlobGasUsed)
+		}
+		header.ExcessBlobGas = &excessBlobGas
+		header.ParentBeaconRoot = new(common.Hash)
+	}
+	return header, nil
+}
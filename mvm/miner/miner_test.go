@@ -0,0 +1,196 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip1559"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+var (
+	testConfig   = params.MergedTestChainConfig
+	testSigner   = types.LatestSigner(testConfig)
+	testCoinbase = common.HexToAddress("0xc014ba5e")
+	testTo       = common.HexToAddress("0xdead")
+
+	keyA, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	keyB, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	keyC, _ = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
+	keyD, _ = crypto.HexToECDSA("0202020202020202020202020202020202020202020202020202020202020202")
+)
+
+func addr(key *ecdsa.PrivateKey) common.Address {
+	return crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// newTestState returns a state funding the accounts A, B and C.
+func newTestState() *state.StateDB {
+	statedb := state.NewAccountStateDb()
+	for _, key := range []*ecdsa.PrivateKey{keyA, keyB, keyC} {
+		statedb.AddBalance(addr(key), uint256.NewInt(params.InitialBaseFee*10_000_000), tracing.BalanceChangeUnspecified)
+	}
+	statedb.IntermediateRoot(true)
+	return statedb
+}
+
+func newParent(gasLimit uint64) *types.Header {
+	zero := uint64(0)
+	return &types.Header{
+		Number:        new(big.Int),
+		Difficulty:    new(big.Int),
+		GasLimit:      gasLimit,
+		BaseFee:       big.NewInt(params.InitialBaseFee),
+		ExcessBlobGas: &zero,
+		BlobGasUsed:   &zero,
+	}
+}
+
+func transfer(key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+	return types.MustSignNewTx(key, testSigner, &types.DynamicFeeTx{
+		ChainID:   testConfig.ChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(params.InitialBaseFee * 2),
+		Gas:       params.TxGas,
+		To:        &testTo,
+		Value:     big.NewInt(1),
+	})
+}
+
+func blobTx(key *ecdsa.PrivateKey, nonce uint64, blobs int) *types.Transaction {
+	hashes := make([]common.Hash, blobs)
+	for i := range hashes {
+		hashes[i] = common.Hash{0x01, byte(i + 1)}
+	}
+	return types.MustSignNewTx(key, testSigner, &types.BlobTx{
+		ChainID:    uint256.MustFromBig(testConfig.ChainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(params.InitialBaseFee * 2),
+		Gas:        params.TxGas,
+		To:         testTo,
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: hashes,
+	})
+}
+
+// checkBlock re-executes the block on a fresh state and validates the header.
+func checkBlock(t *testing.T, res *Result) {
+	t.Helper()
+	processed, err := mvm.NewStateProcessor(testConfig, nil).Process(res.Block, newTestState(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process built block: %v", err)
+	}
+	if err := mvm.ValidateState(res.Block.Header(), processed); err != nil {
+		t.Fatalf("built block is invalid: %v", err)
+	}
+	for i, receipt := range res.Receipts {
+		if receipt.BlockHash != res.Block.Hash() {
+			t.Errorf("receipt %d: block hash mismatch", i)
+		}
+	}
+}
+
+func TestBuildBlock(t *testing.T) {
+	var (
+		parent  = newParent(30_000_000)
+		statedb = newTestState()
+		pending = map[common.Address][]*types.Transaction{
+			addr(keyA): {transfer(keyA, 1, 1), transfer(keyA, 0, 1)}, // out of order
+			addr(keyB): {transfer(keyB, 0, 5)},
+			addr(keyC): {transfer(keyC, 1, 10)}, // nonce gap
+			addr(keyD): {transfer(keyD, 0, 20)}, // no funds
+		}
+		miner = New(testConfig, nil, Config{Coinbase: testCoinbase, ExtraData: []byte("mixchain")})
+	)
+	res, err := miner.BuildBlock(parent, statedb, 12, pending)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	block := res.Block
+	want := []common.Hash{pending[addr(keyB)][0].Hash(), pending[addr(keyA)][1].Hash(), pending[addr(keyA)][0].Hash()}
+	if len(block.Transactions()) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(block.Transactions()), len(want))
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Hash() != want[i] {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, tx.Hash(), want[i])
+		}
+	}
+	header := block.Header()
+	if header.ParentHash != parent.Hash() || header.Number.Uint64() != 1 || header.Time != 12 || header.Coinbase != testCoinbase {
+		t.Errorf("header fields mismatch: %+v", header)
+	}
+	if want := eip1559.CalcBaseFee(testConfig, parent); header.BaseFee.Cmp(want) != 0 {
+		t.Errorf("base fee mismatch: have %v, want %v", header.BaseFee, want)
+	}
+	if header.GasUsed != 3*params.TxGas || *header.BlobGasUsed != 0 {
+		t.Errorf("gas used mismatch: have %d, blob %d", header.GasUsed, *header.BlobGasUsed)
+	}
+	if header.Root != statedb.IntermediateRoot(true) {
+		t.Errorf("state root mismatch")
+	}
+	if len(pending[addr(keyA)]) != 2 {
+		t.Errorf("pending transactions modified")
+	}
+	checkBlock(t, res)
+}
+
+func TestBuildBlockGasLimit(t *testing.T) {
+	var (
+		parent  = newParent(50_000)
+		pending = map[common.Address][]*types.Transaction{
+			addr(keyA): {transfer(keyA, 0, 3), transfer(keyA, 1, 3)},
+			addr(keyB): {transfer(keyB, 0, 2)},
+		}
+	)
+	res, err := New(testConfig, nil, Config{}).BuildBlock(parent, newTestState(), 12, pending)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if have := len(res.Block.Transactions()); have != 2 {
+		t.Fatalf("transaction count mismatch: have %d, want 2", have)
+	}
+	if res.Block.GasLimit() != parent.GasLimit || res.Block.GasUsed() != 2*params.TxGas {
+		t.Errorf("gas mismatch: limit %d, used %d", res.Block.GasLimit(), res.Block.GasUsed())
+	}
+	checkBlock(t, res)
+
+	// The gas ceiling moves the gas limit towards the target.
+	res, err = New(testConfig, nil, Config{GasCeil: 60_000}).BuildBlock(parent, newTestState(), 12, nil)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if want := mvm.CalcGasLimit(parent.GasLimit, 60_000); res.Block.GasLimit() != want || want <= parent.GasLimit {
+		t.Errorf("gas limit mismatch: have %d, want %d", res.Block.GasLimit(), want)
+	}
+}
+
+func TestBuildBlockBlobLimit(t *testing.T) {
+	pending := map[common.Address][]*types.Transaction{
+		addr(keyA): {blobTx(keyA, 0, 4)},
+		addr(keyB): {blobTx(keyB, 0, 4)},
+		addr(keyC): {blobTx(keyC, 0, 2)},
+	}
+	res, err := New(testConfig, nil, Config{}).BuildBlock(newParent(30_000_000), newTestState(), 12, pending)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if have := len(res.Block.Transactions()); have != 2 {
+		t.Fatalf("transaction count mismatch: have %d, want 2", have)
+	}
+	if have := *res.Block.BlobGasUsed(); have != params.MaxBlobGasPerBlock {
+		t.Errorf("blob gas used mismatch: have %d, want %d", have, params.MaxBlobGasPerBlock)
+	}
+	checkBlock(t, res)
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"math/big"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/types"
+)
+
+// txWithMinerFee wraps a transaction with the sender it was signed by.
+type txWithMinerFee struct {
+	tx   *types.Transaction
+	from common.Address
+}
+
+// txByPriceAndTime implements both the sort and the heap interface, making it useful
+// for all at once sorting as well as individually adding and removing elements.
+type txByPriceAndTime struct {
+	txs     []*txWithMinerFee
+	baseFee *big.Int
+}
+
+func (s *txByPriceAndTime) Len() int { return len(s.txs) }
+func (s *txByPriceAndTime) Less(i, j int) bool {
+	// If the prices are equal, use the time the transaction was first seen for
+	// deterministic sorting
+	cmp := s.txs[i].tx.EffectiveGasTipCmp(s.txs[j].tx, s.baseFee)
+	if cmp == 0 {
+		return s.txs[i].tx.Time().Before(s.txs[j].tx.Time())
+	}
+	return cmp > 0
+}
+func (s *txByPriceAndTime) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
+
+func (s *txByPriceAndTime) Push(x interface{}) {
+	s.txs = append(s.txs, x.(*txWithMinerFee))
+}
+
+func (s *txByPriceAndTime) Pop() interface{} {
+	old := s.txs
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	s.txs = old[0 : n-1]
+	return x
+}
+
+// transactionsByPriceAndNonce represents a set of transactions that can return
+// transactions in a profit-maximizing sorted order, while supporting removing
+// entire batches of transactions for non-executable accounts.
+type transactionsByPriceAndNonce struct {
+	txs     map[common.Address][]*types.Transaction // Per account nonce-sorted list of transactions
+	heads   *txByPriceAndTime                       // Next transaction for each unique account (price heap)
+	baseFee *big.Int                                // Current base fee
+}
+
+// newTransactionsByPriceAndNonce creates a transaction set that can retrieve
+// price sorted transactions in a nonce-honouring way. Transactions which can't
+// pay the base fee are dropped together with the later ones of the account.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func newTransactionsByPriceAndNonce(txs map[common.Address][]*types.Transaction, baseFee *big.Int) *transactionsByPriceAndNonce {
+	// Initialize a price and received time based heap with the head transactions
+	heads := &txByPriceAndTime{txs: make([]*txWithMinerFee, 0, len(txs)), baseFee: baseFee}
+	for from, accTxs := range txs {
+		if len(accTxs) == 0 || !payable(accTxs[0], baseFee) {
+			delete(txs, from)
+			continue
+		}
+		heads.txs = append(heads.txs, &txWithMinerFee{tx: accTxs[0], from: from})
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(heads)
+
+	// Assemble and return the transaction set
+	return &transactionsByPriceAndNonce{
+		txs:     txs,
+		heads:   heads,
+		baseFee: baseFee,
+	}
+}
+
+// payable reports whether the fee cap of the transaction covers the base fee.
+func payable(tx *types.Transaction, baseFee *big.Int) bool {
+	return baseFee == nil || tx.GasFeeCapIntCmp(baseFee) >= 0
+}
+
+// Peek returns the next transaction by price.
+func (t *transactionsByPriceAndNonce) Peek() (*types.Transaction, common.Address) {
+	if t.heads.Len() == 0 {
+		return nil, common.Address{}
+	}
+	return t.heads.txs[0].tx, t.heads.txs[0].from
+}
+
+// Shift replaces the current best head with the next one from the same account.
+func (t *transactionsByPriceAndNonce) Shift() {
+	acc := t.heads.txs[0].from
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 && payable(txs[0], t.baseFee) {
+		t.heads.txs[0], t.txs[acc] = &txWithMinerFee{tx: txs[0], from: acc}, txs[1:]
+		heap.Fix(t.heads, 0)
+		return
+	}
+	heap.Pop(t.heads)
+}
+
+// Pop removes the best transaction, *not* replacing it with the next one from
+// the same account. This should be used when a transaction cannot be executed
+// and hence all subsequent ones should be discarded from the same account.
+func (t *transactionsByPriceAndNonce) Pop() {
+	heap.Pop(t.heads)
+}
+
+// Empty returns if the price heap is empty. It can be used to check it simpler
+// than calling peek and checking for nil return.
+func (t *transactionsByPriceAndNonce) Empty() bool {
+	return t.heads.Len() == 0
+}
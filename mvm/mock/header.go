@@ -23,7 +23,7 @@ func GetHeader(blockNum, difficulty int64, timeStamp uint64) *types.Header {
 		Extra:            nil,
 		MixDigest:        common.Hash{},
 		Nonce:            types.BlockNonce{},
-		BaseFee:          nil,
+		BaseFee:          new(big.Int),
 		WithdrawalsHash:  nil,
 		BlobGasUsed:      nil,
 		ExcessBlobGas:    nil,
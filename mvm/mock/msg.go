@@ -11,11 +11,11 @@ func GetMessage(from common.Address) *mvm.Message {
 		To:                nil,
 		From:              from,
 		Nonce:             0,
-		Value:             nil,
+		Value:             new(big.Int),
 		GasLimit:          0,
 		GasPrice:          new(big.Int).Set(big.NewInt(10)),
-		GasFeeCap:         nil,
-		GasTipCap:         nil,
+		GasFeeCap:         new(big.Int).Set(big.NewInt(10)),
+		GasTipCap:         new(big.Int).Set(big.NewInt(10)),
 		Data:              nil,
 		AccessList:        nil,
 		BlobGasFeeCap:     nil,
@@ -0,0 +1,128 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errHeaderNotFound = errors.New("header not found")
+	errNotCanonical   = errors.New("hash is not currently canonical")
+)
+
+// apiBackend implements ethapi.Backend on top of a node. As every accepted
+// transaction is sealed right away, the pending, safe and finalized blocks are
+// all the head block.
+type apiBackend struct {
+	n *Node
+}
+
+func (b *apiBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(b.n.config.TxPool.PriceLimit), nil
+}
+
+func (b *apiBackend) RPCGasCap() uint64 {
+	return b.n.config.RPCGasCap
+}
+
+func (b *apiBackend) RPCEVMTimeout() time.Duration {
+	return b.n.config.RPCEVMTimeout
+}
+
+func (b *apiBackend) RPCTxFeeCap() float64 {
+	return b.n.config.RPCTxFeeCap
+}
+
+func (b *apiBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	switch number {
+	case rpc.PendingBlockNumber, rpc.LatestBlockNumber, rpc.FinalizedBlockNumber, rpc.SafeBlockNumber:
+		return b.n.chain.CurrentBlock(), nil
+	case rpc.EarliestBlockNumber:
+		return b.n.chain.Genesis().Header(), nil
+	}
+	return b.n.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *apiBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.n.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *apiBackend) CurrentHeader() *types.Header {
+	return b.n.chain.CurrentHeader()
+}
+
+func (b *apiBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	header, err := b.HeaderByNumber(ctx, number)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	return b.n.chain.GetBlock(header.Hash(), header.Number.Uint64()), nil
+}
+
+func (b *apiBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.n.chain.GetBlockByHash(hash), nil
+}
+
+func (b *apiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	var header *types.Header
+	if number, ok := blockNrOrHash.Number(); ok {
+		header, _ = b.HeaderByNumber(ctx, number)
+	} else if hash, ok := blockNrOrHash.Hash(); ok {
+		header = b.n.chain.GetHeaderByHash(common.Hash(hash))
+		if header != nil && blockNrOrHash.RequireCanonical && b.n.chain.GetCanonicalHash(header.Number.Uint64()) != common.Hash(hash) {
+			return nil, nil, errNotCanonical
+		}
+	} else {
+		return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if header == nil {
+		return nil, nil, errHeaderNotFound
+	}
+	statedb, err := b.n.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+func (b *apiBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.n.chain.GetReceiptsByHash(hash), nil
+}
+
+func (b *apiBackend) SubscribeChainHeadEvent(ch chan<- *types.Header) event.Subscription {
+	return b.n.scope.Track(b.n.headFeed.Subscribe(ch))
+}
+
+func (b *apiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.n.scope.Track(b.n.logsFeed.Subscribe(ch))
+}
+
+func (b *apiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.n.sendTx(signedTx)
+}
+
+func (b *apiBackend) GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, blockHash, blockNumber, index := b.n.chain.GetTransaction(txHash)
+	return tx != nil, tx, blockHash, blockNumber, index, nil
+}
+
+func (b *apiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return b.n.txpool.Get(hash)
+}
+
+func (b *apiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return b.n.txpool.Nonce(addr), nil
+}
+
+func (b *apiBackend) ChainConfig() *params.ChainConfig {
+	return b.n.chain.Config()
+}
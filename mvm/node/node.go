@@ -0,0 +1,192 @@
+// Package node runs a single mixchain node serving the eth_ JSON-RPC namespace
+// over HTTP and WebSocket.
+package node
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/ethapi"
+	"github.com/a1146910248/mixchain/mvm/miner"
+	"github.com/a1146910248/mixchain/mvm/txpool"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Config contains the settings of the RPC server and the block production of
+// a node.
+type Config struct {
+	HTTPAddr    string   // Listening address of the HTTP and WebSocket server ("host:port")
+	HTTPCors    []string // Origins allowed to send cross-origin HTTP requests
+	WSOrigins   []string // Origins allowed to open WebSocket connections
+	RPCGasCap   uint64   // Global gas cap for eth_call and eth_estimateGas
+	RPCTxFeeCap float64  // Global fee cap in ether for submitted transactions
+
+	RPCEVMTimeout time.Duration // Global timeout for eth_call
+
+	TxPool txpool.Config
+	Miner  miner.Config
+}
+
+// DefaultConfig contains reasonable default settings for a local node.
+var DefaultConfig = Config{
+	HTTPAddr:      "127.0.0.1:8545",
+	RPCGasCap:     50000000,
+	RPCTxFeeCap:   1,
+	RPCEVMTimeout: 5 * time.Second,
+	TxPool:        txpool.DefaultConfig,
+}
+
+// Node is a single node chain. Every transaction accepted into the pool is
+// sealed into a new block right away, so the pending state is always the head
+// state once the submission returns.
+type Node struct {
+	config Config
+	chain  *mvm.BlockChain
+	txpool *txpool.TxPool
+	miner  *miner.Miner
+
+	mu sync.Mutex // Serialises block production and pool updates
+
+	headFeed event.Feed
+	logsFeed event.Feed
+	scope    event.SubscriptionScope
+
+	rpc      *rpc.Server
+	server   *http.Server
+	listener net.Listener
+}
+
+// New opens the chain in the given database, initialising it with the genesis
+// spec if empty, and registers the RPC APIs. The server is not started.
+func New(db ethdb.KeyValueStore, genesis *mvm.Genesis, config Config) (*Node, error) {
+	chain, err := mvm.NewBlockChain(db, genesis, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	head := chain.CurrentBlock()
+	statedb, err := chain.StateAt(head.Root)
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{
+		config: config,
+		chain:  chain,
+		txpool: txpool.New(config.TxPool, chain.Config(), head, statedb),
+		miner:  miner.New(chain.Config(), chain, config.Miner),
+		rpc:    rpc.NewServer(),
+	}
+	for _, api := range ethapi.GetAPIs(&apiBackend{n}) {
+		if err := n.rpc.RegisterName(api.Namespace, api.Service); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// Start opens the listener of the HTTP and WebSocket server and starts serving.
+func (n *Node) Start() error {
+	if n.listener != nil {
+		return errors.New("node already started")
+	}
+	listener, err := net.Listen("tcp", n.config.HTTPAddr)
+	if err != nil {
+		return err
+	}
+	n.listener = listener
+	n.server = &http.Server{Handler: newHTTPHandler(n.rpc, n.config.HTTPCors, n.config.WSOrigins)}
+	go n.server.Serve(listener)
+
+	log.Info("HTTP server started", "endpoint", n.HTTPEndpoint())
+	log.Info("WebSocket enabled", "url", n.WSEndpoint())
+	return nil
+}
+
+// Close stops the server and terminates all subscriptions.
+func (n *Node) Close() error {
+	n.scope.Close()
+	n.rpc.Stop()
+	if n.server != nil {
+		return n.server.Close()
+	}
+	return nil
+}
+
+// HTTPEndpoint returns the URL of the HTTP server.
+func (n *Node) HTTPEndpoint() string {
+	return fmt.Sprintf("http://%s", n.listener.Addr())
+}
+
+// WSEndpoint returns the URL of the WebSocket server.
+func (n *Node) WSEndpoint() string {
+	return fmt.Sprintf("ws://%s", n.listener.Addr())
+}
+
+// Attach creates an in-process RPC client of the node.
+func (n *Node) Attach() *rpc.Client {
+	return rpc.DialInProc(n.rpc)
+}
+
+// Blockchain returns the chain of the node.
+func (n *Node) Blockchain() *mvm.BlockChain {
+	return n.chain
+}
+
+// sendTx adds a transaction to the pool and seals the executable transactions
+// into a new block.
+func (n *Node) sendTx(tx *types.Transaction) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.txpool.Add([]*types.Transaction{tx})[0]; err != nil {
+		return err
+	}
+	return n.seal()
+}
+
+// seal builds a block of the pending transactions on top of the head, makes it
+// the new head and moves the pool along. The caller must hold mu.
+func (n *Node) seal() error {
+	pending := n.txpool.Pending()
+	if len(pending) == 0 {
+		return nil
+	}
+	head := n.chain.CurrentBlock()
+	statedb, err := n.chain.StateAt(head.Root)
+	if err != nil {
+		return err
+	}
+	res, err := n.miner.BuildBlock(head, statedb, uint64(time.Now().Unix()), pending)
+	if err != nil {
+		return err
+	}
+	if err := n.chain.WriteBlockAndSetHead(res.Block, res.Receipts, statedb); err != nil {
+		return err
+	}
+	log.Info("Sealed new block", "number", res.Block.Number(), "hash", res.Block.Hash(), "txs", len(res.Block.Transactions()), "gas", res.Block.GasUsed())
+
+	head = n.chain.CurrentBlock()
+	if statedb, err = n.chain.StateAt(head.Root); err != nil {
+		return err
+	}
+	n.txpool.Reset(head, statedb)
+
+	var logs []*types.Log
+	for _, receipt := range res.Receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	n.headFeed.Send(head)
+	if len(logs) > 0 {
+		n.logsFeed.Send(logs)
+	}
+	return nil
+}
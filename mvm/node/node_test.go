@@ -0,0 +1,267 @@
+package node
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/hexutil"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+
+	// testLogger emits a single log with logTopic and holds 0x02 in slot 0x01.
+	testLogger = common.HexToAddress("0x1000")
+	logTopic   = common.HexToHash("0xa1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1")
+	logCode    = append(append([]byte{byte(vm.PUSH32)}, logTopic.Bytes()...),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.LOG1), byte(vm.STOP))
+
+	// testReverter reverts with Error("oops").
+	testReverter = common.HexToAddress("0x2000")
+)
+
+// revertCode reverts with the abi encoded Error(string) of the given reason.
+func revertCode(reason string) []byte {
+	payload := common.FromHex("0x08c379a0")
+	payload = append(payload, common.LeftPadBytes([]byte{0x20}, 32)...)
+	payload = append(payload, common.LeftPadBytes(big.NewInt(int64(len(reason))).Bytes(), 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), (len(reason)+31)/32*32)...)
+
+	return append([]byte{
+		byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0x00, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), 0x00, byte(vm.REVERT),
+	}, payload...)
+}
+
+func newTestNode(t *testing.T) *Node {
+	t.Helper()
+
+	genesis := &mvm.Genesis{
+		Config:   params.AllDevChainProtocolChanges,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+		Alloc: types.GenesisAlloc{
+			testAddr:     {Balance: testBalance},
+			testLogger:   {Code: logCode, Storage: map[common.Hash]common.Hash{common.HexToHash("0x01"): common.HexToHash("0x02")}},
+			testReverter: {Code: revertCode("oops")},
+		},
+	}
+	config := DefaultConfig
+	config.HTTPAddr = "127.0.0.1:0"
+
+	n, err := New(rawdb.NewMemoryDatabase(), genesis, config)
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	t.Cleanup(func() { n.Close() })
+	return n
+}
+
+func dial(t *testing.T, endpoint string) *rpc.Client {
+	t.Helper()
+
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		t.Fatalf("could not dial %s: %v", endpoint, err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// sendTx signs a dynamic fee transaction from testAddr with the pending nonce
+// and submits it with eth_sendRawTransaction.
+func sendTx(t *testing.T, client *rpc.Client, to *common.Address, value *big.Int, gas uint64) *types.Transaction {
+	t.Helper()
+
+	var nonce hexutil.Uint64
+	if err := client.Call(&nonce, "eth_getTransactionCount", testAddr, "pending"); err != nil {
+		t.Fatalf("could not get nonce: %v", err)
+	}
+	config := params.AllDevChainProtocolChanges
+	tx, err := types.SignNewTx(testKey, types.LatestSigner(config), &types.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     uint64(nonce),
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+		Gas:       gas,
+		To:        to,
+		Value:     value,
+	})
+	if err != nil {
+		t.Fatalf("could not sign tx: %v", err)
+	}
+	raw, _ := tx.MarshalBinary()
+
+	var hash common.Hash
+	if err := client.Call(&hash, "eth_sendRawTransaction", hexutil.Bytes(raw)); err != nil {
+		t.Fatalf("could not send tx: %v", err)
+	}
+	if hash != tx.Hash() {
+		t.Fatalf("tx hash mismatch: have %x, want %x", hash, tx.Hash())
+	}
+	return tx
+}
+
+func TestNodeState(t *testing.T) {
+	n := newTestNode(t)
+	client := dial(t, n.HTTPEndpoint())
+
+	var chainID hexutil.Big
+	if err := client.Call(&chainID, "eth_chainId"); err != nil {
+		t.Fatalf("eth_chainId failed: %v", err)
+	}
+	if chainID.ToInt().Cmp(params.AllDevChainProtocolChanges.ChainID) != 0 {
+		t.Errorf("chain id mismatch: have %v, want %v", chainID.ToInt(), params.AllDevChainProtocolChanges.ChainID)
+	}
+	var number hexutil.Uint64
+	if err := client.Call(&number, "eth_blockNumber"); err != nil || number != 0 {
+		t.Errorf("eth_blockNumber: have %d, %v, want 0", number, err)
+	}
+	var balance hexutil.Big
+	if err := client.Call(&balance, "eth_getBalance", testAddr, "latest"); err != nil || balance.ToInt().Cmp(testBalance) != 0 {
+		t.Errorf("eth_getBalance: have %v, %v, want %v", balance.ToInt(), err, testBalance)
+	}
+	var code hexutil.Bytes
+	if err := client.Call(&code, "eth_getCode", testLogger, "latest"); err != nil || string(code) != string(logCode) {
+		t.Errorf("eth_getCode: have %x, %v, want %x", code, err, logCode)
+	}
+	var slot hexutil.Bytes
+	if err := client.Call(&slot, "eth_getStorageAt", testLogger, "0x1", "latest"); err != nil || common.BytesToHash(slot) != common.HexToHash("0x02") {
+		t.Errorf("eth_getStorageAt: have %x, %v, want 0x02", slot, err)
+	}
+	var nonce hexutil.Uint64
+	if err := client.Call(&nonce, "eth_getTransactionCount", testAddr, "0x0"); err != nil || nonce != 0 {
+		t.Errorf("eth_getTransactionCount: have %d, %v, want 0", nonce, err)
+	}
+	if err := client.Call(&balance, "eth_getBalance", testAddr, "0x5"); err == nil || err.Error() != errHeaderNotFound.Error() {
+		t.Errorf("eth_getBalance of unknown block: have %v, want %v", err, errHeaderNotFound)
+	}
+}
+
+func TestNodeCall(t *testing.T) {
+	n := newTestNode(t)
+	client := dial(t, n.HTTPEndpoint())
+
+	var gas hexutil.Uint64
+	if err := client.Call(&gas, "eth_estimateGas", map[string]interface{}{"from": testAddr, "to": common.HexToAddress("0x0102"), "value": "0x1"}); err != nil || uint64(gas) != params.TxGas {
+		t.Errorf("eth_estimateGas of transfer: have %d, %v, want %d", gas, err, params.TxGas)
+	}
+	if err := client.Call(&gas, "eth_estimateGas", map[string]interface{}{"from": testAddr, "to": testLogger}); err != nil || uint64(gas) <= params.TxGas {
+		t.Errorf("eth_estimateGas of log call: have %d, %v", gas, err)
+	}
+	var out hexutil.Bytes
+	err := client.Call(&out, "eth_call", map[string]interface{}{"from": testAddr, "to": testReverter}, "latest")
+	if err == nil {
+		t.Fatal("eth_call of reverting contract succeeded")
+	}
+	if have, want := err.Error(), "execution reverted: oops"; have != want {
+		t.Errorf("revert error mismatch: have %q, want %q", have, want)
+	}
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != 3 {
+		t.Errorf("revert error code mismatch: have %v", err)
+	}
+	if dataErr, ok := err.(rpc.DataError); !ok || dataErr.ErrorData() == nil {
+		t.Errorf("revert error has no data: %v", err)
+	}
+	if err := client.Call(&out, "eth_estimateGas", map[string]interface{}{"from": testAddr, "to": testReverter}); err == nil || err.Error() != "execution reverted: oops" {
+		t.Errorf("eth_estimateGas of reverting contract: have %v", err)
+	}
+}
+
+func TestNodeTransaction(t *testing.T) {
+	n := newTestNode(t)
+	client := dial(t, n.HTTPEndpoint())
+
+	tx := sendTx(t, client, &testLogger, new(big.Int), 50000)
+
+	var number hexutil.Uint64
+	if err := client.Call(&number, "eth_blockNumber"); err != nil || number != 1 {
+		t.Fatalf("eth_blockNumber: have %d, %v, want 1", number, err)
+	}
+	var receipt *types.Receipt
+	if err := client.Call(&receipt, "eth_getTransactionReceipt", tx.Hash()); err != nil || receipt == nil {
+		t.Fatalf("eth_getTransactionReceipt: have %v, %v", receipt, err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful || receipt.BlockNumber.Uint64() != 1 || len(receipt.Logs) != 1 {
+		t.Errorf("unexpected receipt: status %d, block %v, logs %d", receipt.Status, receipt.BlockNumber, len(receipt.Logs))
+	}
+	var nonce hexutil.Uint64
+	if err := client.Call(&nonce, "eth_getTransactionCount", testAddr, "latest"); err != nil || nonce != 1 {
+		t.Errorf("eth_getTransactionCount: have %d, %v, want 1", nonce, err)
+	}
+	var logs []*types.Log
+	if err := client.Call(&logs, "eth_getLogs", map[string]interface{}{"fromBlock": "0x0", "address": testLogger, "topics": []common.Hash{logTopic}}); err != nil {
+		t.Fatalf("eth_getLogs failed: %v", err)
+	}
+	if len(logs) != 1 || logs[0].TxHash != tx.Hash() || logs[0].BlockNumber != 1 {
+		t.Errorf("unexpected logs: %v", logs)
+	}
+	if err := client.Call(&logs, "eth_getLogs", map[string]interface{}{"fromBlock": "0x0", "topics": []common.Hash{{}}}); err != nil || len(logs) != 0 {
+		t.Errorf("eth_getLogs of unknown topic: have %d logs, %v", len(logs), err)
+	}
+	// A replayed transaction is rejected by the pool.
+	raw, _ := tx.MarshalBinary()
+	var hash common.Hash
+	if err := client.Call(&hash, "eth_sendRawTransaction", hexutil.Bytes(raw)); err == nil {
+		t.Error("replayed transaction accepted")
+	}
+}
+
+func TestNodeSubscriptions(t *testing.T) {
+	n := newTestNode(t)
+	client := dial(t, n.WSEndpoint())
+	ctx := context.Background()
+
+	heads := make(chan *types.Header, 1)
+	headSub, err := client.EthSubscribe(ctx, heads, "newHeads")
+	if err != nil {
+		t.Fatalf("could not subscribe to new heads: %v", err)
+	}
+	defer headSub.Unsubscribe()
+
+	logs := make(chan *types.Log, 1)
+	logSub, err := client.EthSubscribe(ctx, logs, "logs", map[string]interface{}{"address": testLogger})
+	if err != nil {
+		t.Fatalf("could not subscribe to logs: %v", err)
+	}
+	defer logSub.Unsubscribe()
+
+	tx := sendTx(t, client, &testLogger, new(big.Int), 50000)
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case head := <-heads:
+		if head.Number.Uint64() != 1 {
+			t.Errorf("head number mismatch: have %v, want 1", head.Number)
+		}
+	case err := <-headSub.Err():
+		t.Fatalf("head subscription failed: %v", err)
+	case <-timeout:
+		t.Fatal("no new head received")
+	}
+	select {
+	case log := <-logs:
+		if log.TxHash != tx.Hash() || log.Topics[0] != logTopic {
+			t.Errorf("unexpected log: %v", log)
+		}
+	case err := <-logSub.Err():
+		t.Fatalf("log subscription failed: %v", err)
+	case <-timeout:
+		t.Fatal("no log received")
+	}
+}
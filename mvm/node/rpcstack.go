@@ -0,0 +1,80 @@
+package node
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// httpHandler serves JSON-RPC over HTTP and WebSocket on the same endpoint,
+// upgrading a request to WebSocket when the client asks for it.
+type httpHandler struct {
+	http http.Handler
+	ws   http.Handler
+}
+
+func newHTTPHandler(srv *rpc.Server, cors []string, wsOrigins []string) http.Handler {
+	return &httpHandler{
+		http: newCorsHandler(srv, cors),
+		ws:   srv.WebsocketHandler(wsOrigins),
+	}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWebsocket(r) {
+		h.ws.ServeHTTP(w, r)
+		return
+	}
+	h.http.ServeHTTP(w, r)
+}
+
+// isWebsocket checks the header of an http request for a websocket upgrade request.
+func isWebsocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// corsHandler adds the CORS headers to the responses of requests coming from
+// one of the allowed origins and answers their preflight requests.
+type corsHandler struct {
+	origins map[string]struct{}
+	any     bool
+	next    http.Handler
+}
+
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
+	// disable CORS support if user has not specified a custom CORS configuration
+	if len(allowedOrigins) == 0 {
+		return srv
+	}
+	h := &corsHandler{origins: make(map[string]struct{}), next: srv}
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			h.any = true
+		}
+		h.origins[strings.ToLower(origin)] = struct{}{}
+	}
+	return h
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if _, ok := h.origins[strings.ToLower(origin)]; origin == "" || !(ok || h.any) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Set("Access-Control-Allow-Origin", origin)
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		header.Set("Access-Control-Allow-Methods", "POST, GET")
+		if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", reqHeaders)
+		}
+		header.Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadCode retrieves the contract code of the provided code hash.
+func ReadCode(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(codeKey(hash))
+	return data
+}
+
+// HasCode checks if the contract code corresponding to the
+// provided code hash is present in the db.
+func HasCode(db ethdb.KeyValueReader, hash common.Hash) bool {
+	ok, _ := db.Has(codeKey(hash))
+	return ok
+}
+
+// WriteCode writes the provided contract code database.
+func WriteCode(db ethdb.KeyValueWriter, hash common.Hash, code []byte) {
+	if err := db.Put(codeKey(hash), code); err != nil {
+		log.Crit("Failed to store contract code", "err", err)
+	}
+}
+
+// ReadTrieNode retrieves the trie node of the provided hash.
+func ReadTrieNode(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(hash.Bytes())
+	return data
+}
+
+// HasTrieNode checks if the trie node with the provided hash is present in db.
+func HasTrieNode(db ethdb.KeyValueReader, hash common.Hash) bool {
+	ok, _ := db.Has(hash.Bytes())
+	return ok
+}
+
+// WriteTrieNode writes the provided trie node database.
+func WriteTrieNode(db ethdb.KeyValueWriter, hash common.Hash, node []byte) {
+	if err := db.Put(hash.Bytes(), node); err != nil {
+		log.Crit("Failed to store trie node", "err", err)
+	}
+}
+
+// ReadHeadStateRoot retrieves the state root of the latest commit. A zero hash
+// is returned if nothing has been committed yet.
+func ReadHeadStateRoot(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(headStateRootKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteHeadStateRoot stores the state root of the latest commit.
+func WriteHeadStateRoot(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Put(headStateRootKey, root.Bytes()); err != nil {
+		log.Crit("Failed to store last state root", "err", err)
+	}
+}
+
+// ReadHeadStateNumber retrieves the sequence number of the latest commit, nil
+// if nothing has been committed yet.
+func ReadHeadStateNumber(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(headStateNumberKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteHeadStateNumber stores the sequence number of the latest commit.
+func WriteHeadStateNumber(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(headStateNumberKey, encodeNumber(number)); err != nil {
+		log.Crit("Failed to store last state number", "err", err)
+	}
+}
+
+// ReadStateHistory retrieves the state root committed with the given sequence
+// number. A zero hash is returned if there is no such commit.
+func ReadStateHistory(db ethdb.KeyValueReader, number uint64) common.Hash {
+	data, _ := db.Get(stateHistoryKey(number))
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteStateHistory stores the state root committed with the given sequence
+// number.
+func WriteStateHistory(db ethdb.KeyValueWriter, number uint64, root common.Hash) {
+	if err := db.Put(stateHistoryKey(number), root.Bytes()); err != nil {
+		log.Crit("Failed to store state history", "err", err)
+	}
+}
+
+// DeleteStateHistory removes the state root committed with the given sequence
+// number.
+func DeleteStateHistory(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Delete(stateHistoryKey(number)); err != nil {
+		log.Crit("Failed to delete state history", "err", err)
+	}
+}
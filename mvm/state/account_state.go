@@ -2,49 +2,71 @@ package state
 
 import (
 	"bytes"
-	"encoding/json"
-	"fmt"
 	"github.com/a1146910248/mixchain/crypto"
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
 	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/trie"
 	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
 
+	"fmt"
 	"math/big"
-	"os"
+	"sort"
 )
 
-var emptyCodeHash = crypto.Sha256(nil)
+var emptyCodeHash = types.EmptyCodeHash.Bytes()
 
 type accountObject struct {
-	Address      common.Address              `json:"address,omitempty"`
-	AddrHash     common.Hash                 `json:"addr_hash,omitempty"` // hash of ethereum address of the account
-	ByteCode     []byte                      `json:"byte_code,omitempty"`
-	Data         accountData                 `json:"data,omitempty"`
-	CacheStorage map[common.Hash]common.Hash `json:"cache_storage,omitempty"` // 用于缓存存储的变量
+	db           *StateDB
+	Address      common.Address
+	AddrHash     common.Hash // hash of ethereum address of the account
+	ByteCode     []byte      // 合约代码, 第一次访问时从数据库加载
+	Data         accountData
+	CacheStorage map[common.Hash]common.Hash // 已提交的存储, 即交易开始前的值, 第一次访问时从存储树加载
+
+	trie           *trie.Trie                  // 存储树, 第一次访问时打开
+	pendingStorage map[common.Hash]common.Hash // 已Finalise但尚未写入存储树的存储
+	dirtyStorage   map[common.Hash]common.Hash // 当前交易中修改过但尚未提交的存储
+	dirtyCode      bool                        // 代码被修改过, Commit时需要写入数据库
+
+	// 以下标记只在当前交易内有效, Finalise时重置
+	selfDestructed bool // 账户已执行SELFDESTRUCT, Finalise时删除
+	newContract    bool // 账户在当前交易中通过CreateAccount创建, 用于EIP-6780
+
+	// 账户已在Finalise中删除. 删除的账户依然保留在Accounts中, 以免再次从状态树中加载
+	deleted bool
 }
 
 type accountData struct {
-	Nonce    uint64       `json:"nonce,omitempty"`
-	Balance  *uint256.Int `json:"balance,omitempty"`
-	Root     common.Hash  `json:"root,omitempty"` // merkle root of the storage trie
-	CodeHash []byte       `json:"code_hash,omitempty"`
+	Nonce    uint64
+	Balance  *uint256.Int
+	Root     common.Hash // merkle root of the storage trie
+	CodeHash []byte
 }
 
 // newObject creates a state object.
-func newAccountObject(address common.Address, data accountData) *accountObject {
+func newAccountObject(db *StateDB, address common.Address, data accountData) *accountObject {
 	if data.Balance == nil {
 		data.Balance = new(uint256.Int)
 	}
 	if data.CodeHash == nil {
 		data.CodeHash = emptyCodeHash
 	}
+	if data.Root == (common.Hash{}) {
+		data.Root = types.EmptyRootHash
+	}
 	return &accountObject{
-		Address:      address,
-		AddrHash:     common.BytesToHash(crypto.Sha256(address[:])),
-		Data:         data,
-		CacheStorage: make(map[common.Hash]common.Hash),
+		db:             db,
+		Address:        address,
+		AddrHash:       crypto.Keccak256Hash(address[:]),
+		Data:           data,
+		CacheStorage:   make(map[common.Hash]common.Hash),
+		pendingStorage: make(map[common.Hash]common.Hash),
+		dirtyStorage:   make(map[common.Hash]common.Hash),
 	}
 }
 
@@ -53,18 +75,8 @@ func (object *accountObject) Balance() *uint256.Int {
 	return object.Data.Balance
 }
 
-func (object *accountObject) SubBalance(amount *uint256.Int) {
-	if amount.Sign() == 0 {
-		return
-	}
-	object.Data.Balance = new(uint256.Int).Sub(object.Balance(), amount)
-}
-
-func (object *accountObject) AddBalance(amount *uint256.Int) {
-	if amount.Sign() == 0 {
-		return
-	}
-	object.Data.Balance = new(uint256.Int).Add(object.Balance(), amount)
+func (object *accountObject) setBalance(amount *uint256.Int) {
+	object.Data.Balance = amount
 }
 
 // Nonce nonce--
@@ -82,53 +94,311 @@ func (object *accountObject) CodeHash() []byte {
 	return object.Data.CodeHash
 }
 
+// Code 返回合约代码, 尚未加载时按代码hash从数据库读取
 func (object *accountObject) Code() []byte {
-	return object.ByteCode
+	if object.ByteCode != nil {
+		return object.ByteCode
+	}
+	if bytes.Equal(object.CodeHash(), emptyCodeHash) {
+		return nil
+	}
+	code, err := object.db.db.ContractCode(common.BytesToHash(object.CodeHash()))
+	if err != nil {
+		object.db.setError(fmt.Errorf("can't load code hash %x: %v", object.CodeHash(), err))
+	}
+	object.ByteCode = code
+	return code
 }
 
 func (object *accountObject) SetCode(codeHash []byte, code []byte) {
 	object.Data.CodeHash = codeHash
 	object.ByteCode = code
+	object.dirtyCode = true
 }
 
 // GetStorageState storage sate-------
+// 优先返回当前交易中的修改, 否则返回已提交的值
 func (object *accountObject) GetStorageState(key common.Hash) common.Hash {
+	if value, dirty := object.dirtyStorage[key]; dirty {
+		return value
+	}
+	return object.GetCommittedStorageState(key)
+}
+
+// GetCommittedStorageState 返回已提交的存储值, 忽略当前交易中的修改.
+// 不在缓存中的存储槽从存储树中读取, 读到的值(包括零值)会被缓存
+func (object *accountObject) GetCommittedStorageState(key common.Hash) common.Hash {
 	value, exist := object.CacheStorage[key]
 	if exist {
-		// fmt.Println("exist cache ", " key: ", key, " value: ", value)
 		return value
 	}
-	return common.Hash{}
+	if object.Data.Root != types.EmptyRootHash {
+		tr, err := object.getTrie()
+		if err != nil {
+			object.db.setError(err)
+			return common.Hash{}
+		}
+		enc, err := tr.Get(crypto.Keccak256(key[:]))
+		if err != nil {
+			object.db.setError(err)
+			return common.Hash{}
+		}
+		if len(enc) > 0 {
+			_, content, _, err := rlp.Split(enc)
+			if err != nil {
+				object.db.setError(err)
+			}
+			value.SetBytes(content)
+		}
+	}
+	object.CacheStorage[key] = value
+	return value
 }
 
 func (object *accountObject) SetStorageState(key, value common.Hash) {
-	if object.CacheStorage == nil {
-		object.CacheStorage = make(map[common.Hash]common.Hash)
+	if object.dirtyStorage == nil {
+		object.dirtyStorage = make(map[common.Hash]common.Hash)
 	}
-	object.CacheStorage[key] = value
+	object.dirtyStorage[key] = value
+}
+
+// getTrie 返回账户的存储树, 第一次调用时按Data.Root打开
+func (object *accountObject) getTrie() (*trie.Trie, error) {
+	if object.trie == nil {
+		tr, err := object.db.db.OpenStorageTrie(object.AddrHash, object.Data.Root)
+		if err != nil {
+			return nil, err
+		}
+		object.trie = tr
+	}
+	return object.trie, nil
+}
+
+// updateTrie 将pendingStorage写入存储树, key为keccak(slot), value为去掉前导零后的RLP编码.
+// 没有待写入的存储时返回已打开的存储树(可能为nil)
+func (object *accountObject) updateTrie() (*trie.Trie, error) {
+	if len(object.pendingStorage) == 0 {
+		return object.trie, nil
+	}
+	tr, err := object.getTrie()
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range object.pendingStorage {
+		if value == (common.Hash{}) {
+			err = tr.Delete(crypto.Keccak256(key[:]))
+		} else {
+			v, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+			err = tr.Update(crypto.Keccak256(key[:]), v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	object.pendingStorage = make(map[common.Hash]common.Hash)
+	return tr, nil
+}
+
+// updateRoot 将待写入的存储写入存储树并更新Data.Root
+func (object *accountObject) updateRoot() {
+	tr, err := object.updateTrie()
+	if err != nil {
+		object.db.setError(err)
+		return
+	}
+	if tr != nil {
+		object.Data.Root = tr.Hash()
+	}
+}
+
+// commit 将存储树中修改过的节点写入w
+func (object *accountObject) commit(w ethdb.KeyValueWriter) error {
+	tr, err := object.updateTrie()
+	if err != nil || tr == nil {
+		return err
+	}
+	root, err := tr.Commit(w)
+	if err != nil {
+		return err
+	}
+	object.Data.Root = root
+	return nil
+}
+
+// stateAccount 返回账户在状态树中的共识表示
+func (object *accountObject) stateAccount() *types.StateAccount {
+	return &types.StateAccount{
+		Nonce:    object.Data.Nonce,
+		Balance:  object.Data.Balance,
+		Root:     object.Data.Root,
+		CodeHash: object.Data.CodeHash,
+	}
+}
+
+// finalise 将当前交易中修改过的存储提升为已提交的存储, 并记录下来等待写入存储树.
+// 零值也保留在CacheStorage中, 以免再次从存储树中读到旧值
+func (object *accountObject) finalise() {
+	if len(object.dirtyStorage) == 0 {
+		return
+	}
+	for key, value := range object.dirtyStorage {
+		object.CacheStorage[key] = value
+		object.pendingStorage[key] = value
+	}
+	object.dirtyStorage = make(map[common.Hash]common.Hash)
 }
 
 func (object *accountObject) Empty() bool {
 	return object.Data.Nonce == 0 && object.Data.Balance.Sign() == 0 && bytes.Equal(object.Data.CodeHash, emptyCodeHash)
 }
 
-// StateDB 实现vm的StateDB的接口 用于进行测试
+type revision struct {
+	id           int
+	journalIndex int
+}
+
+// StateDB 实现vm的StateDB的接口.
+// 账户, 代码和存储在第一次访问时从Database中加载, Commit时只写入修改过的部分
 type StateDB struct {
-	Accounts map[common.Address]*accountObject `json:"accounts,omitempty"`
+	db           Database
+	trie         *trie.Trie  // 账户状态树
+	originalRoot common.Hash // 打开或最近一次Commit时的状态根
+
+	Accounts        map[common.Address]*accountObject
+	accountsPending map[common.Address]struct{} // 已Finalise但尚未写入状态树的账户
+	accountsDirty   map[common.Address]struct{} // 自上次Commit以来修改过的账户
+
+	// DB error.
+	// State objects are used by the consensus core and VM which are
+	// unable to deal with database-level errors. Any error that occurs
+	// during a database read is memoized here and will eventually be
+	// returned by StateDB.Commit.
+	dbErr error
+
+	// Journal of state modifications. This is the backbone of
+	// Snapshot and RevertToSnapshot.
+	journal        *journal
+	validRevisions []revision
+	nextRevisionId int
+
+	// The refund counter, also used by state transitioning.
+	refund uint64
+
+	// Rules of the transaction being executed, set by Prepare.
+	rules params.Rules
+
+	// Per-transaction access list
+	accessList *accessList
+
+	// Transient storage (EIP-1153), cleared at the start of every transaction
+	transientStorage transientStorage
+
+	// 当前交易的上下文, 由SetTxContext设置, 用于给日志编号
+	thash   common.Hash
+	txIndex int
+	logs    map[common.Hash][]*types.Log
+	logSize uint
+}
+
+// New 打开状态根为root的状态, 账户和存储在第一次访问时从db中加载.
+// root不是最近一次提交的状态根时, 打开的历史状态可以修改和执行交易(例如在历史区块上eth_call),
+// 修改只保存在内存中, Commit会返回ErrHistoricalState, 数据库中的历史状态不会被改变
+func New(root common.Hash, db Database) (*StateDB, error) {
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &StateDB{
+		db:               db,
+		trie:             tr,
+		originalRoot:     root,
+		Accounts:         make(map[common.Address]*accountObject),
+		accountsPending:  make(map[common.Address]struct{}),
+		accountsDirty:    make(map[common.Address]struct{}),
+		journal:          newJournal(),
+		accessList:       newAccessList(),
+		transientStorage: newTransientStorage(),
+		logs:             make(map[common.Hash][]*types.Log),
+	}, nil
+}
+
+// NewFromHead 打开db中最近一次Commit的状态, 从未提交过时返回空状态
+func NewFromHead(db Database) (*StateDB, error) {
+	return New(headRoot(db.DiskDB()), db)
+}
+
+// Open 打开path下的LevelDB数据库并加载最近一次Commit的状态.
+// 使用完毕后需要通过Database().DiskDB().Close()关闭数据库
+func Open(path string) (*StateDB, error) {
+	disk, err := rawdb.NewLevelDBDatabase(path, 16, 16, "", false)
+	if err != nil {
+		return nil, err
+	}
+	stateDB, err := NewFromHead(NewDatabase(disk))
+	if err != nil {
+		disk.Close()
+		return nil, err
+	}
+	return stateDB, nil
 }
 
-// NewAccountStateDb new instance
+// NewAccountStateDb 创建一个基于内存数据库的空状态
 func NewAccountStateDb() *StateDB {
-	return &StateDB{
-		Accounts: make(map[common.Address]*accountObject),
+	stateDB, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()))
+	return stateDB
+}
+
+// ReadOnly 状态是否为历史状态(不是最近一次提交的状态), 历史状态的修改不能Commit
+func (accSt *StateDB) ReadOnly() bool {
+	return headRoot(accSt.db.DiskDB()) != accSt.originalRoot
+}
+
+// Database 返回状态所使用的数据库
+func (accSt *StateDB) Database() Database {
+	return accSt.db
+}
+
+// setError remembers the first non-nil error it is called with.
+func (accSt *StateDB) setError(err error) {
+	if accSt.dbErr == nil {
+		accSt.dbErr = err
 	}
 }
 
+// Error returns the memorized database failure occurred earlier.
+func (accSt *StateDB) Error() error {
+	return accSt.dbErr
+}
+
+// getAccountObject 返回账户对象, 不在内存中时从状态树中加载, 账户不存在或已删除时返回nil
 func (accSt *StateDB) getAccountObject(addr common.Address) *accountObject {
-	if value, exist := accSt.Accounts[addr]; exist {
-		return value
+	if obj, exist := accSt.Accounts[addr]; exist {
+		if obj.deleted {
+			return nil
+		}
+		return obj
 	}
-	return nil
+	enc, err := accSt.trie.Get(crypto.Keccak256(addr[:]))
+	if err != nil {
+		accSt.setError(fmt.Errorf("getAccountObject (%x) error: %w", addr[:], err))
+		return nil
+	}
+	if len(enc) == 0 {
+		return nil
+	}
+	data := new(types.StateAccount)
+	if err := rlp.DecodeBytes(enc, data); err != nil {
+		accSt.setError(fmt.Errorf("can't decode account %x: %v", addr[:], err))
+		return nil
+	}
+	obj := newAccountObject(accSt, addr, accountData{
+		Nonce:    data.Nonce,
+		Balance:  data.Balance,
+		Root:     data.Root,
+		CodeHash: data.CodeHash,
+	})
+	accSt.setAccountObject(obj)
+	return obj
 }
 
 func (accSt *StateDB) setAccountObject(obj *accountObject) {
@@ -141,46 +411,61 @@ func (accSt *StateDB) getOrsetAccountObject(addr common.Address) *accountObject
 	if get != nil {
 		return get
 	}
-	set := newAccountObject(addr, accountData{})
-	accSt.setAccountObject(set)
-	return set
+	return accSt.createAccountObject(addr)
 }
-func New() (*StateDB, error) {
-	stateDB := new(StateDB)
-	stateDB.CreateAccount(common.Address{})
-	return stateDB, nil
+
+// createAccountObject 创建新的账户对象并记录到journal中, 以便回滚时删除(或恢复被覆盖的已删除账户)
+func (accSt *StateDB) createAccountObject(addr common.Address) *accountObject {
+	prev := accSt.Accounts[addr]
+	obj := newAccountObject(accSt, addr, accountData{})
+	accSt.journal.append(createObjectChange{account: &addr, prev: prev})
+	accSt.setAccountObject(obj)
+	return obj
 }
 
 // 实现接口-------
 
-// CreateAccount 创建一个新的合约账户
+// CreateAccount 创建一个新的合约账户, 账户已存在时保留原有数据.
+// 无论哪种情况账户都会被标记为在当前交易中创建, 以便EIP-6780的SELFDESTRUCT可以删除它
 func (accSt *StateDB) CreateAccount(addr common.Address) {
-	if accSt.getAccountObject(addr) != nil {
-		return
+	obj := accSt.getAccountObject(addr)
+	if obj == nil {
+		obj = accSt.createAccountObject(addr)
+	} else if !obj.newContract {
+		accSt.journal.append(createContractChange{account: &addr})
 	}
-	obj := newAccountObject(addr, accountData{})
-	accSt.setAccountObject(obj)
+	obj.newContract = true
 }
 
 // SubBalance 减去某个账户的余额
 func (accSt *StateDB) SubBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
 	stateObject := accSt.getOrsetAccountObject(addr)
-	if stateObject != nil {
-		stateObject.SubBalance(amount)
+	if stateObject != nil && !amount.IsZero() {
+		accSt.journal.append(balanceChange{account: &addr, prev: stateObject.Balance()})
+		stateObject.setBalance(new(uint256.Int).Sub(stateObject.Balance(), amount))
 	}
 }
 
 // AddBalance 增加某个账户的余额
 func (accSt *StateDB) AddBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
 	stateObject := accSt.getOrsetAccountObject(addr)
-	if stateObject != nil {
-		stateObject.AddBalance(amount)
+	if stateObject == nil {
+		return
+	}
+	// 零值转账只是touch账户, 使其在Finalise时可以作为空账户被删除(EIP-158)
+	if amount.IsZero() {
+		if stateObject.Empty() {
+			accSt.journal.append(touchChange{account: &addr})
+		}
+		return
 	}
+	accSt.journal.append(balanceChange{account: &addr, prev: stateObject.Balance()})
+	stateObject.setBalance(new(uint256.Int).Add(stateObject.Balance(), amount))
 }
 
 // GetBalance 获取某个账户的余额
 func (accSt *StateDB) GetBalance(addr common.Address) *uint256.Int {
-	stateObject := accSt.getOrsetAccountObject(addr)
+	stateObject := accSt.getAccountObject(addr)
 	if stateObject != nil {
 		return stateObject.Balance()
 	}
@@ -200,6 +485,7 @@ func (accSt *StateDB) GetNonce(addr common.Address) uint64 {
 func (accSt *StateDB) SetNonce(addr common.Address, nonce uint64) {
 	stateObject := accSt.getOrsetAccountObject(addr)
 	if stateObject != nil {
+		accSt.journal.append(nonceChange{account: &addr, prev: stateObject.Nonce()})
 		stateObject.SetNonce(nonce)
 	}
 }
@@ -226,7 +512,12 @@ func (accSt *StateDB) GetCode(addr common.Address) []byte {
 func (accSt *StateDB) SetCode(addr common.Address, code []byte) {
 	stateObject := accSt.getOrsetAccountObject(addr)
 	if stateObject != nil {
-		stateObject.SetCode(crypto.Sha256(code), code)
+		accSt.journal.append(codeChange{
+			account:  &addr,
+			prevhash: stateObject.CodeHash(),
+			prevcode: stateObject.Code(),
+		})
+		stateObject.SetCode(crypto.Keccak256(code), code)
 	}
 }
 
@@ -236,23 +527,41 @@ func (accSt *StateDB) GetCodeSize(addr common.Address) int {
 	if stateObject == nil {
 		return 0
 	}
-	if stateObject.ByteCode != nil {
-		return len(stateObject.ByteCode)
-	}
-	return 0
+	return len(stateObject.Code())
 }
 
-// AddRefund 暂时先忽略补偿
-func (accSt *StateDB) AddRefund(uint64) {
-	return
+// AddRefund 增加gas补偿计数
+func (accSt *StateDB) AddRefund(gas uint64) {
+	accSt.journal.append(refundChange{prev: accSt.refund})
+	accSt.refund += gas
 }
 
-// GetRefund ...
+// GetRefund 获取当前交易累计的gas补偿
 func (accSt *StateDB) GetRefund() uint64 {
-	return 0
+	return accSt.refund
 }
-func (accSt *StateDB) SubRefund(uint64) {
-	return
+
+// SubRefund 减少gas补偿计数, 计数低于0时panic
+func (accSt *StateDB) SubRefund(gas uint64) {
+	accSt.journal.append(refundChange{prev: accSt.refund})
+	if gas > accSt.refund {
+		panic(fmt.Sprintf("Refund counter below zero (gas: %d > refund: %d)", gas, accSt.refund))
+	}
+	accSt.refund -= gas
+}
+
+// CappedRefund 返回消耗了gasUsed的交易实际可以获得的补偿, 上限由Prepare传入的规则决定:
+// London(EIP-3529)之后为gasUsed/5, 之前为gasUsed/2
+func (accSt *StateDB) CappedRefund(gasUsed uint64) uint64 {
+	quotient := params.RefundQuotient
+	if accSt.rules.IsLondon {
+		quotient = params.RefundQuotientEIP3529
+	}
+	refund := gasUsed / quotient
+	if refund > accSt.refund {
+		refund = accSt.refund
+	}
+	return refund
 }
 
 // GetState 和SetState 是用于保存合约执行时 存储的变量是否发生变化 evm对变量存储的改变消耗的gas是有区别的
@@ -268,7 +577,11 @@ func (accSt *StateDB) GetState(addr common.Address, key common.Hash) common.Hash
 func (accSt *StateDB) SetState(addr common.Address, key common.Hash, value common.Hash) {
 	stateObject := accSt.getOrsetAccountObject(addr)
 	if stateObject != nil {
-		fmt.Printf("SetState key: %x value: %s", key, new(big.Int).SetBytes(value[:]).String())
+		prev := stateObject.GetStorageState(key)
+		if prev == value {
+			return
+		}
+		accSt.journal.append(storageChange{account: &addr, key: key, prevalue: prev})
 		stateObject.SetStorageState(key, value)
 	}
 }
@@ -284,19 +597,98 @@ func (accSt *StateDB) Empty(addr common.Address) bool {
 	return so == nil || so.Empty()
 }
 
-// RevertToSnapshot ...
-func (accSt *StateDB) RevertToSnapshot(int) {
+// RevertToSnapshot 回滚到指定的快照, 撤销快照之后所有通过journal记录的修改
+func (accSt *StateDB) RevertToSnapshot(revid int) {
+	// Find the snapshot in the stack of valid snapshots.
+	idx := sort.Search(len(accSt.validRevisions), func(i int) bool {
+		return accSt.validRevisions[i].id >= revid
+	})
+	if idx == len(accSt.validRevisions) || accSt.validRevisions[idx].id != revid {
+		panic(fmt.Errorf("revision id %v cannot be reverted", revid))
+	}
+	snapshot := accSt.validRevisions[idx].journalIndex
 
+	// Replay the journal to undo changes and remove invalidated snapshots
+	accSt.journal.revert(accSt, snapshot)
+	accSt.validRevisions = accSt.validRevisions[:idx]
 }
 
-// Snapshot ...
+// Snapshot 创建一个快照, 返回的id可用于RevertToSnapshot
 func (accSt *StateDB) Snapshot() int {
-	return 0
+	id := accSt.nextRevisionId
+	accSt.nextRevisionId++
+	accSt.validRevisions = append(accSt.validRevisions, revision{id, accSt.journal.length()})
+	return id
 }
 
-// AddLog 添加事件触发日志
+// SetTxContext 设置当前执行交易的hash和在区块中的序号, 在执行交易之前调用
+func (accSt *StateDB) SetTxContext(thash common.Hash, ti int) {
+	accSt.thash = thash
+	accSt.txIndex = ti
+}
+
+// TxIndex 返回当前交易在区块中的序号
+func (accSt *StateDB) TxIndex() int {
+	return accSt.txIndex
+}
+
+// AddLog 添加事件触发日志, 并填入交易hash, 交易序号和日志在区块中的序号
 func (accSt *StateDB) AddLog(log *types.Log) {
-	//fmt.Printf("log: %v", log)
+	accSt.journal.append(addLogChange{txhash: accSt.thash})
+
+	log.TxHash = accSt.thash
+	log.TxIndex = uint(accSt.txIndex)
+	log.Index = accSt.logSize
+	accSt.logs[accSt.thash] = append(accSt.logs[accSt.thash], log)
+	accSt.logSize++
+}
+
+// GetLogs 返回指定交易产生的日志, 并填入区块信息
+func (accSt *StateDB) GetLogs(hash common.Hash, blockNumber uint64, blockHash common.Hash) []*types.Log {
+	logs := accSt.logs[hash]
+	for _, l := range logs {
+		l.BlockNumber = blockNumber
+		l.BlockHash = blockHash
+	}
+	return logs
+}
+
+// Logs 返回所有交易产生的日志
+func (accSt *StateDB) Logs() []*types.Log {
+	var logs []*types.Log
+	for _, lgs := range accSt.logs {
+		logs = append(logs, lgs...)
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].Index < logs[j].Index
+	})
+	return logs
+}
+
+// MakeReceipt 根据当前交易(SetTxContext)收集到的日志构造收据.
+// 合约地址和实际gas价格等与交易本身相关的字段由调用者填写
+func (accSt *StateDB) MakeReceipt(txType uint8, failed bool, gasUsed, cumulativeGasUsed uint64, blockNumber *big.Int, blockHash common.Hash) *types.Receipt {
+	receipt := &types.Receipt{
+		Type:              txType,
+		CumulativeGasUsed: cumulativeGasUsed,
+		TxHash:            accSt.thash,
+		GasUsed:           gasUsed,
+		BlockHash:         blockHash,
+		TransactionIndex:  uint(accSt.txIndex),
+	}
+	if failed {
+		receipt.Status = types.ReceiptStatusFailed
+	} else {
+		receipt.Status = types.ReceiptStatusSuccessful
+	}
+	var number uint64
+	if blockNumber != nil {
+		receipt.BlockNumber = new(big.Int).Set(blockNumber)
+		number = blockNumber.Uint64()
+	}
+	receipt.Logs = accSt.GetLogs(accSt.thash, number, blockHash)
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt
 }
 
 // AddPreimage 暂时没搞清楚这个是干嘛用的
@@ -311,88 +703,262 @@ func (accSt *StateDB) ForEachStorage(common.Address, func(common.Hash, common.Ha
 
 /*******************************************************************************************************/
 
-// 新的实现
-func (accSt *StateDB) GetCommittedState(common.Address, common.Hash) common.Hash {
-	// 将bincode写入文件
-	file, err := os.Create("./account_sate.db")
-	if err != nil {
-		return common.Hash{1}
-	}
-	err = json.NewEncoder(file).Encode(accSt)
-	//fmt.Println("len(binCode): ", len(binCode), " code: ", binCode)
-	// bufW := bufio.NewWriter(file)
-	// bufW.Write(binCode)
-	// // bufW.WriteByte('\n')
-	// bufW.Flush()
-	file.Close()
+// GetCommittedState 返回交易开始前(最近一次Finalise时)的存储值, 用于SSTORE的净gas计算
+func (accSt *StateDB) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	stateObject := accSt.getAccountObject(addr)
+	if stateObject != nil {
+		return stateObject.GetCommittedStorageState(key)
+	}
 	return common.Hash{}
 }
+
+// GetStorageRoot 返回账户已提交存储的存储树根, 账户不存在时返回空hash
 func (accSt *StateDB) GetStorageRoot(addr common.Address) common.Hash {
-	return common.Hash{}
+	stateObject := accSt.getAccountObject(addr)
+	if stateObject == nil {
+		return common.Hash{}
+	}
+	stateObject.updateRoot()
+	return stateObject.Data.Root
 }
+
+// GetTransientState 获取账户的临时存储(EIP-1153)
 func (accSt *StateDB) GetTransientState(addr common.Address, key common.Hash) common.Hash {
-	return common.Hash{}
+	return accSt.transientStorage.Get(addr, key)
 }
 
+// SetTransientState 设置账户的临时存储, 修改会记录到journal中以便回滚
 func (accSt *StateDB) SetTransientState(addr common.Address, key, value common.Hash) {
-	return
+	prev := accSt.GetTransientState(addr, key)
+	if prev == value {
+		return
+	}
+	accSt.journal.append(transientStorageChange{
+		account:  &addr,
+		key:      key,
+		prevalue: prev,
+	})
+	accSt.setTransientState(addr, key, value)
 }
 
-func (accSt *StateDB) SelfDestruct(common.Address) {
-	return
+// setTransientState 不记录journal的底层setter, 回滚时使用
+func (accSt *StateDB) setTransientState(addr common.Address, key, value common.Hash) {
+	accSt.transientStorage.Set(addr, key, value)
 }
-func (accSt *StateDB) HasSelfDestructed(common.Address) bool {
+
+// SelfDestruct 标记账户已自毁并清空余额, 账户在Finalise之前依然存在
+func (accSt *StateDB) SelfDestruct(addr common.Address) {
+	stateObject := accSt.getAccountObject(addr)
+	if stateObject == nil {
+		return
+	}
+	accSt.journal.append(selfDestructChange{
+		account:     &addr,
+		prev:        stateObject.selfDestructed,
+		prevbalance: stateObject.Balance(),
+	})
+	stateObject.selfDestructed = true
+	stateObject.setBalance(new(uint256.Int))
+}
+
+// HasSelfDestructed 账户是否在当前交易中自毁
+func (accSt *StateDB) HasSelfDestructed(addr common.Address) bool {
+	stateObject := accSt.getAccountObject(addr)
+	if stateObject != nil {
+		return stateObject.selfDestructed
+	}
 	return false
 }
-func (accSt *StateDB) Selfdestruct6780(common.Address) {
 
+// Selfdestruct6780 Cancun之后的SELFDESTRUCT(EIP-6780), 只有在同一交易中创建的合约才会被删除
+func (accSt *StateDB) Selfdestruct6780(addr common.Address) {
+	stateObject := accSt.getAccountObject(addr)
+	if stateObject == nil {
+		return
+	}
+	if stateObject.newContract {
+		accSt.SelfDestruct(addr)
+	}
 }
+
+// AddressInAccessList 地址是否已在访问列表中(EIP-2929 warm)
 func (accSt *StateDB) AddressInAccessList(addr common.Address) bool {
-	return false
+	return accSt.accessList.ContainsAddress(addr)
 }
+
+// SlotInAccessList 分别返回地址和存储槽是否已在访问列表中
 func (accSt *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
-	return false, false
+	return accSt.accessList.Contains(addr, slot)
 }
 
+// AddAddressToAccessList 将地址加入访问列表
 func (accSt *StateDB) AddAddressToAccessList(addr common.Address) {
+	if accSt.accessList.AddAddress(addr) {
+		accSt.journal.append(accessListAddAccountChange{&addr})
+	}
 }
 
+// AddSlotToAccessList 将(地址, 存储槽)加入访问列表
 func (accSt *StateDB) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	addrMod, slotMod := accSt.accessList.AddSlot(addr, slot)
+	if addrMod {
+		// In practice, this should not happen, since there is no way to enter the
+		// scope of 'address' without having the 'address' become already added
+		// to the access list (via call-variant, create, etc).
+		// Better safe than sorry, though
+		accSt.journal.append(accessListAddAccountChange{&addr})
+	}
+	if slotMod {
+		accSt.journal.append(accessListAddSlotChange{
+			address: &addr,
+			slot:    &slot,
+		})
+	}
 }
+
+// Prepare 在执行每笔交易之前调用, 记录当前规则, 重置补偿计数并清空临时存储(EIP-1153).
+//
+// Berlin之后还会重建访问列表:
+// - 加入发送者, 接收者(创建合约时在evm.create中加入)和预编译合约
+// - 加入交易自带的access list (EIP-2930)
+// - Shanghai之后加入coinbase (EIP-3651)
 func (accSt *StateDB) Prepare(rules params.Rules, sender, coinbase common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	accSt.rules = rules
+	accSt.refund = 0
+
+	if rules.IsBerlin {
+		// Clear out any leftover from previous executions
+		al := newAccessList()
+		accSt.accessList = al
+
+		al.AddAddress(sender)
+		if dest != nil {
+			al.AddAddress(*dest)
+		}
+		for _, addr := range precompiles {
+			al.AddAddress(addr)
+		}
+		for _, el := range txAccesses {
+			al.AddAddress(el.Address)
+			for _, key := range el.StorageKeys {
+				al.AddSlot(el.Address, key)
+			}
+		}
+		if rules.IsShanghai {
+			al.AddAddress(coinbase)
+		}
+	}
+	// Reset transient storage at the beginning of transaction execution
+	accSt.transientStorage = newTransientStorage()
+}
+
+// Finalise 在每笔交易结束时调用, 删除已自毁的账户并将修改过的存储提升为已提交的存储,
+// deleteEmptyObjects为true时(EIP-158)同时删除被修改过的空账户.
+// 交易之间不允许回滚, 所以journal和补偿计数也会被清空.
+func (accSt *StateDB) Finalise(deleteEmptyObjects bool) {
+	for addr := range accSt.journal.dirties {
+		obj, exist := accSt.Accounts[addr]
+		if !exist || obj.deleted {
+			continue
+		}
+		if obj.selfDestructed || (deleteEmptyObjects && obj.Empty()) {
+			obj.deleted = true
+		} else {
+			obj.finalise()
+		}
+		obj.newContract = false
+		accSt.accountsPending[addr] = struct{}{}
+		accSt.accountsDirty[addr] = struct{}{}
+	}
+	accSt.clearJournalAndRefund()
+}
+
+// IntermediateRoot 结束当前交易(Finalise), 将修改过的账户写入状态树并计算状态树的根.
+// 状态树的key为keccak(address), value为types.StateAccount的RLP编码, 与以太坊的状态根计算方式一致
+func (accSt *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
+	accSt.Finalise(deleteEmptyObjects)
+
+	for addr := range accSt.accountsPending {
+		obj := accSt.Accounts[addr]
+		if obj.deleted {
+			accSt.deleteAccountObject(obj)
+		} else {
+			obj.updateRoot()
+			accSt.updateAccountObject(obj)
+		}
+	}
+	if len(accSt.accountsPending) > 0 {
+		accSt.accountsPending = make(map[common.Address]struct{})
+	}
+	return accSt.trie.Hash()
 }
 
-// Commit 进行持久换存储
-func (accSt *StateDB) Commit() error {
-	// 将bincode写入文件
-	file, err := os.Create("cmd/mvmdebug/account_sate.db")
+// updateAccountObject 将账户写入状态树
+func (accSt *StateDB) updateAccountObject(obj *accountObject) {
+	data, err := rlp.EncodeToBytes(obj.stateAccount())
 	if err != nil {
-		return err
+		panic(fmt.Errorf("can't encode object at %x: %v", obj.Address[:], err))
+	}
+	if err := accSt.trie.Update(obj.AddrHash[:], data); err != nil {
+		accSt.setError(fmt.Errorf("updateAccountObject (%x) error: %v", obj.Address[:], err))
 	}
-	err = json.NewEncoder(file).Encode(accSt)
-	//fmt.Println("len(binCode): ", len(binCode), " code: ", binCode)
-	// bufW := bufio.NewWriter(file)
-	// bufW.Write(binCode)
-	// // bufW.WriteByte('\n')
-	// bufW.Flush()
-	file.Close()
-	return err
 }
 
-// TryLoadFromDisk  尝试从磁盘加载AccountState
-func TryLoadFromDisk() (*StateDB, error) {
-	file, err := os.Open("cmd/mvmdebug/account_sate.db")
-	if err != nil && os.IsNotExist(err) {
-		return NewAccountStateDb(), nil
-	}
-	if err != nil {
-		return nil, err
+// deleteAccountObject 将账户从状态树中删除
+func (accSt *StateDB) deleteAccountObject(obj *accountObject) {
+	if err := accSt.trie.Delete(obj.AddrHash[:]); err != nil {
+		accSt.setError(fmt.Errorf("deleteAccountObject (%x) error: %v", obj.Address[:], err))
 	}
+}
 
-	// stat, _ := file.Stat()
-	// // buf := stat.Size()
-	var accStat StateDB
+func (accSt *StateDB) clearJournalAndRefund() {
+	if len(accSt.journal.entries) > 0 {
+		accSt.journal = newJournal()
+		accSt.refund = 0
+	}
+	accSt.validRevisions = accSt.validRevisions[:0] // Snapshots can be created without journal entries
+}
 
-	err = json.NewDecoder(file).Decode(&accStat)
-	return &accStat, err
+// Commit 结束当前交易并将状态写入数据库, 返回新的状态根.
+// 只有自上次Commit以来修改过的账户代码和树节点会被写入, 所有写入通过一个batch一次完成,
+// 新的状态根同时被记录为一个新的版本并成为head(见NewFromHead, History).
+// 历史状态不能Commit, 返回ErrHistoricalState
+func (accSt *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
+	if accSt.dbErr != nil {
+		return common.Hash{}, fmt.Errorf("commit aborted due to earlier error: %v", accSt.dbErr)
+	}
+	if accSt.ReadOnly() {
+		return common.Hash{}, ErrHistoricalState
+	}
+	accSt.IntermediateRoot(deleteEmptyObjects)
+	if accSt.dbErr != nil {
+		return common.Hash{}, accSt.dbErr
+	}
+	batch := accSt.db.DiskDB().NewBatch()
+	for addr := range accSt.accountsDirty {
+		obj := accSt.Accounts[addr]
+		if obj.deleted {
+			continue
+		}
+		if obj.dirtyCode {
+			if len(obj.ByteCode) > 0 {
+				rawdb.WriteCode(batch, common.BytesToHash(obj.CodeHash()), obj.ByteCode)
+			}
+			obj.dirtyCode = false
+		}
+		if err := obj.commit(batch); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	root, err := accSt.trie.Commit(batch)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	writeHistory(accSt.db.DiskDB(), batch, root)
+	if err := batch.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	accSt.accountsDirty = make(map[common.Address]struct{})
+	accSt.originalRoot = root
+	return root, nil
 }
@@ -0,0 +1,93 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/lru"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/trie"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// Cache size granted for caching clean code.
+	codeCacheSize = 64 * 1024 * 1024
+)
+
+// Database wraps access to tries and contract code.
+type Database interface {
+	// OpenTrie opens the main account trie.
+	OpenTrie(root common.Hash) (*trie.Trie, error)
+
+	// OpenStorageTrie opens the storage trie of an account.
+	OpenStorageTrie(addrHash, root common.Hash) (*trie.Trie, error)
+
+	// ContractCode retrieves a particular contract's code.
+	ContractCode(codeHash common.Hash) ([]byte, error)
+
+	// DiskDB returns the underlying key-value disk database.
+	DiskDB() ethdb.KeyValueStore
+}
+
+// NewDatabase creates a backing store for state. The returned database is safe for
+// concurrent use, but does not retain any recent trie nodes in memory, every
+// node is read from the key-value store. The store can be an in-memory one
+// (rawdb.NewMemoryDatabase) or an on-disk one (rawdb.NewLevelDBDatabase).
+func NewDatabase(db ethdb.KeyValueStore) Database {
+	return &cachingDB{
+		disk:      db,
+		codeCache: lru.NewSizeConstrainedCache[common.Hash, []byte](codeCacheSize),
+	}
+}
+
+type cachingDB struct {
+	disk      ethdb.KeyValueStore
+	codeCache *lru.SizeConstrainedCache[common.Hash, []byte]
+}
+
+// OpenTrie opens the main account trie at a specific root hash.
+func (db *cachingDB) OpenTrie(root common.Hash) (*trie.Trie, error) {
+	return trie.New(root, db.disk)
+}
+
+// OpenStorageTrie opens the storage trie of an account. Storage tries share the
+// node namespace with the account trie, so addrHash is not used for the lookup.
+func (db *cachingDB) OpenStorageTrie(addrHash, root common.Hash) (*trie.Trie, error) {
+	return trie.New(root, db.disk)
+}
+
+// ContractCode retrieves a particular contract's code.
+func (db *cachingDB) ContractCode(codeHash common.Hash) ([]byte, error) {
+	code, _ := db.codeCache.Get(codeHash)
+	if len(code) > 0 {
+		return code, nil
+	}
+	code = rawdb.ReadCode(db.disk, codeHash)
+	if len(code) > 0 {
+		db.codeCache.Add(codeHash, code)
+		return code, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// DiskDB returns the underlying key-value disk database.
+func (db *cachingDB) DiskDB() ethdb.KeyValueStore {
+	return db.disk
+}
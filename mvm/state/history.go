@@ -0,0 +1,78 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// 每次Commit都会在数据库中记录一个新的版本(序号 -> 状态根), 并把它设为head.
+// 历史版本的树节点不会被删除, 所以任意历史状态根都可以通过New打开, 但只有head状态可以Commit.
+
+// ErrHistoricalState 在Commit一个不是head的状态时返回
+var ErrHistoricalState = errors.New("state is not the head state, historical state is read-only")
+
+// headRoot 返回最近一次提交的状态根, 从未提交过时返回空树的根
+func headRoot(db ethdb.KeyValueReader) common.Hash {
+	root := rawdb.ReadHeadStateRoot(db)
+	if root == (common.Hash{}) {
+		return types.EmptyRootHash
+	}
+	return root
+}
+
+// writeHistory 将root记录为一个新的版本并设为head
+func writeHistory(db ethdb.KeyValueStore, w ethdb.KeyValueWriter, root common.Hash) {
+	var number uint64
+	if head := rawdb.ReadHeadStateNumber(db); head != nil {
+		number = *head + 1
+	}
+	rawdb.WriteStateHistory(w, number, root)
+	rawdb.WriteHeadStateNumber(w, number)
+	rawdb.WriteHeadStateRoot(w, root)
+}
+
+// History 按提交顺序返回所有版本的状态根, 最后一个即为head
+func History(db Database) []common.Hash {
+	disk := db.DiskDB()
+	head := rawdb.ReadHeadStateNumber(disk)
+	if head == nil {
+		return nil
+	}
+	roots := make([]common.Hash, 0, *head+1)
+	for number := uint64(0); number <= *head; number++ {
+		roots = append(roots, rawdb.ReadStateHistory(disk, number))
+	}
+	return roots
+}
+
+// Rollback 将head重置为历史中最近一次提交的root, 之后的版本从历史记录中删除.
+// 删除的版本的树节点依然保留在数据库中, 已经打开的状态在回滚之后都变为只读
+func Rollback(db Database, root common.Hash) error {
+	disk := db.DiskDB()
+	head := rawdb.ReadHeadStateNumber(disk)
+	if head == nil {
+		return fmt.Errorf("unknown state root %x", root)
+	}
+	number := *head
+	for rawdb.ReadStateHistory(disk, number) != root {
+		if number == 0 {
+			return fmt.Errorf("unknown state root %x", root)
+		}
+		number--
+	}
+	if _, err := db.OpenTrie(root); err != nil {
+		return err
+	}
+	batch := disk.NewBatch()
+	for n := number + 1; n <= *head; n++ {
+		rawdb.DeleteStateHistory(batch, n)
+	}
+	rawdb.WriteHeadStateNumber(batch, number)
+	rawdb.WriteHeadStateRoot(batch, root)
+	return batch.Write()
+}
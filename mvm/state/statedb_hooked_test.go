@@ -0,0 +1,100 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/holiman/uint256"
+)
+
+// TestHooks is a basic sanity-check of all hooks
+func TestHooks(t *testing.T) {
+	inner := NewAccountStateDb()
+	inner.SetTxContext(common.Hash{0x11}, 100) // For the log
+	var result []string
+	var wants = []string{
+		"0xaa00000000000000000000000000000000000000.balance: 0->100 (0)",
+		"0xaa00000000000000000000000000000000000000.balance: 100->50 (10)",
+		"0xaa00000000000000000000000000000000000000.nonce: 0->1337",
+		"0xaa00000000000000000000000000000000000000.code:  (0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470) ->0x1325 (0xa12ae05590de0c93a00bc7ac773c2fdb621e44f814985e72194f921c0050f728)",
+		"0xaa00000000000000000000000000000000000000.storage slot 0x0000000000000000000000000000000000000000000000000000000000000001: 0x0000000000000000000000000000000000000000000000000000000000000000 ->0x0000000000000000000000000000000000000000000000000000000000000011",
+		"0xaa00000000000000000000000000000000000000.storage slot 0x0000000000000000000000000000000000000000000000000000000000000001: 0x0000000000000000000000000000000000000000000000000000000000000011 ->0x0000000000000000000000000000000000000000000000000000000000000022",
+		"log 100 0x1100000000000000000000000000000000000000000000000000000000000000",
+		"0xaa00000000000000000000000000000000000000.balance: 50->0 (13)",
+	}
+	emitF := func(format string, a ...any) {
+		result = append(result, fmt.Sprintf(format, a...))
+	}
+	var sdb *hookedStateDB
+	sdb = NewHookedState(inner, &tracing.Hooks{
+		OnBalanceChange: func(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+			// The hook runs ahead of the modification.
+			if have := sdb.GetBalance(addr).ToBig(); have.Cmp(prev) != 0 {
+				t.Errorf("balance already modified: have %v, prev %v", have, prev)
+			}
+			emitF("%v.balance: %v->%v (%d)", addr, prev, new, reason)
+		},
+		OnNonceChange: func(addr common.Address, prev, new uint64) {
+			emitF("%v.nonce: %v->%v", addr, prev, new)
+		},
+		OnCodeChange: func(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+			emitF("%v.code: %#x (%v) ->%#x (%v)", addr, prevCode, prevCodeHash, code, codeHash)
+		},
+		OnStorageChange: func(addr common.Address, slot common.Hash, prev, new common.Hash) {
+			emitF("%v.storage slot %v: %v ->%v", addr, slot, prev, new)
+		},
+		OnLog: func(log *types.Log) {
+			emitF("log %v %v", log.TxIndex, log.TxHash)
+		},
+	})
+	addr := common.Address{0xaa}
+	sdb.AddBalance(addr, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	sdb.SubBalance(addr, uint256.NewInt(50), tracing.BalanceChangeTransfer)
+	// Zero amounts and unchanged values are not reported.
+	sdb.AddBalance(addr, new(uint256.Int), tracing.BalanceChangeTouchAccount)
+	sdb.SetNonce(addr, 1337)
+	sdb.SetCode(addr, []byte{0x13, 37})
+	sdb.SetCode(addr, []byte{0x13, 37})
+	sdb.SetState(addr, common.HexToHash("0x01"), common.HexToHash("0x11"))
+	sdb.SetState(addr, common.HexToHash("0x01"), common.HexToHash("0x22"))
+	sdb.SetState(addr, common.HexToHash("0x01"), common.HexToHash("0x22"))
+	sdb.SetTransientState(addr, common.HexToHash("0x02"), common.HexToHash("0x01"))
+	sdb.AddLog(&types.Log{
+		Address: common.Address{0xbb},
+	})
+	// Not created in this transaction, so it survives under EIP-6780.
+	sdb.Selfdestruct6780(addr)
+	sdb.SelfDestruct(addr)
+
+	if len(result) != len(wants) {
+		t.Fatalf("number of tracing events wrong, have %d want %d: %v", len(result), len(wants), result)
+	}
+	for i, want := range wants {
+		if have := result[i]; have != want {
+			t.Fatalf("error event %d\nhave: %v\nwant: %v", i, have, want)
+		}
+	}
+	if have := inner.GetState(addr, common.HexToHash("0x01")); have != common.HexToHash("0x22") {
+		t.Errorf("storage not forwarded: %v", have)
+	}
+}
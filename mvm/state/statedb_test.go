@@ -0,0 +1,549 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/holiman/uint256"
+)
+
+func TestSnapshotRevert(t *testing.T) {
+	var (
+		st   = NewAccountStateDb()
+		addr = common.HexToAddress("0xaaaa")
+		key  = common.HexToHash("0x01")
+	)
+	st.AddBalance(addr, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	st.SetNonce(addr, 1)
+	st.SetState(addr, key, common.HexToHash("0x11"))
+
+	snap := st.Snapshot()
+	st.SubBalance(addr, uint256.NewInt(40), tracing.BalanceChangeUnspecified)
+	st.SetNonce(addr, 2)
+	st.SetCode(addr, []byte{0x60, 0x00})
+	st.SetState(addr, key, common.HexToHash("0x22"))
+
+	created := common.HexToAddress("0xbbbb")
+	st.CreateAccount(created)
+
+	st.RevertToSnapshot(snap)
+
+	if have := st.GetBalance(addr); have.Uint64() != 100 {
+		t.Errorf("balance mismatch: have %v, want 100", have)
+	}
+	if have := st.GetNonce(addr); have != 1 {
+		t.Errorf("nonce mismatch: have %d, want 1", have)
+	}
+	if have := st.GetCode(addr); len(have) != 0 {
+		t.Errorf("code not reverted: %x", have)
+	}
+	if have := st.GetState(addr, key); have != common.HexToHash("0x11") {
+		t.Errorf("storage mismatch: have %x, want 0x11", have)
+	}
+	if st.Exist(created) {
+		t.Errorf("created account survived revert")
+	}
+}
+
+func TestNestedSnapshots(t *testing.T) {
+	var (
+		st   = NewAccountStateDb()
+		addr = common.HexToAddress("0xaaaa")
+	)
+	outer := st.Snapshot()
+	st.SetNonce(addr, 1)
+	inner := st.Snapshot()
+	st.SetNonce(addr, 2)
+
+	st.RevertToSnapshot(inner)
+	if have := st.GetNonce(addr); have != 1 {
+		t.Fatalf("nonce after inner revert: have %d, want 1", have)
+	}
+	st.RevertToSnapshot(outer)
+	if st.Exist(addr) {
+		t.Fatalf("account exists after outer revert")
+	}
+}
+
+func TestInvalidSnapshotId(t *testing.T) {
+	st := NewAccountStateDb()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for unknown revision id")
+		}
+	}()
+	st.RevertToSnapshot(42)
+}
+
+func TestRefundCounter(t *testing.T) {
+	st := NewAccountStateDb()
+	st.Prepare(params.Rules{IsLondon: true}, common.Address{}, common.Address{}, nil, nil, nil)
+
+	st.AddRefund(4800)
+	snap := st.Snapshot()
+	st.AddRefund(1000)
+	st.SubRefund(300)
+	if have := st.GetRefund(); have != 5500 {
+		t.Fatalf("refund mismatch: have %d, want 5500", have)
+	}
+	st.RevertToSnapshot(snap)
+	if have := st.GetRefund(); have != 4800 {
+		t.Fatalf("refund after revert: have %d, want 4800", have)
+	}
+	// London caps the refund at a fifth of the gas used.
+	if have := st.CappedRefund(10000); have != 2000 {
+		t.Errorf("london cap: have %d, want 2000", have)
+	}
+	if have := st.CappedRefund(100000); have != 4800 {
+		t.Errorf("uncapped refund: have %d, want 4800", have)
+	}
+	st.Prepare(params.Rules{}, common.Address{}, common.Address{}, nil, nil, nil)
+	if have := st.GetRefund(); have != 0 {
+		t.Errorf("refund not reset by Prepare: have %d", have)
+	}
+}
+
+func TestAccessList(t *testing.T) {
+	var (
+		st       = NewAccountStateDb()
+		sender   = common.HexToAddress("0x01")
+		dest     = common.HexToAddress("0x02")
+		coinbase = common.HexToAddress("0x03")
+		listed   = common.HexToAddress("0x04")
+		slot     = common.HexToHash("0xaa")
+		rules    = params.Rules{IsBerlin: true, IsShanghai: true}
+	)
+	st.Prepare(rules, sender, coinbase, &dest, []common.Address{common.BytesToAddress([]byte{1})}, types.AccessList{
+		{Address: listed, StorageKeys: []common.Hash{slot}},
+	})
+	for _, addr := range []common.Address{sender, dest, coinbase, listed, common.BytesToAddress([]byte{1})} {
+		if !st.AddressInAccessList(addr) {
+			t.Errorf("address %x missing from access list", addr)
+		}
+	}
+	if addrOk, slotOk := st.SlotInAccessList(listed, slot); !addrOk || !slotOk {
+		t.Errorf("tx access list slot missing: %v %v", addrOk, slotOk)
+	}
+
+	other := common.HexToAddress("0x05")
+	snap := st.Snapshot()
+	st.AddSlotToAccessList(other, slot)
+	if addrOk, slotOk := st.SlotInAccessList(other, slot); !addrOk || !slotOk {
+		t.Fatalf("slot not added: %v %v", addrOk, slotOk)
+	}
+	st.RevertToSnapshot(snap)
+	if st.AddressInAccessList(other) {
+		t.Errorf("address survived revert")
+	}
+
+	// Coinbase is only warm from Shanghai on.
+	st.Prepare(params.Rules{IsBerlin: true}, sender, coinbase, &dest, nil, nil)
+	if st.AddressInAccessList(coinbase) {
+		t.Errorf("coinbase warm before shanghai")
+	}
+	if st.AddressInAccessList(listed) {
+		t.Errorf("access list not cleared by Prepare")
+	}
+}
+
+func TestCommittedState(t *testing.T) {
+	var (
+		st   = NewAccountStateDb()
+		addr = common.HexToAddress("0xaaaa")
+		key  = common.HexToHash("0x01")
+		one  = common.HexToHash("0x11")
+		two  = common.HexToHash("0x22")
+	)
+	st.SetState(addr, key, one)
+	if have := st.GetCommittedState(addr, key); have != (common.Hash{}) {
+		t.Fatalf("dirty value leaked into committed state: %x", have)
+	}
+	st.Finalise(false)
+	if have := st.GetCommittedState(addr, key); have != one {
+		t.Fatalf("committed value mismatch after finalise: have %x, want %x", have, one)
+	}
+	st.SetState(addr, key, two)
+	if have := st.GetState(addr, key); have != two {
+		t.Errorf("dirty value mismatch: have %x, want %x", have, two)
+	}
+	if have := st.GetCommittedState(addr, key); have != one {
+		t.Errorf("committed value changed before finalise: have %x, want %x", have, one)
+	}
+}
+
+func TestFinaliseDeletesTouchedEmptyAccounts(t *testing.T) {
+	var (
+		st    = NewAccountStateDb()
+		empty = common.HexToAddress("0xaaaa")
+		full  = common.HexToAddress("0xbbbb")
+	)
+	st.AddBalance(empty, new(uint256.Int), tracing.BalanceChangeTouchAccount)
+	st.AddBalance(full, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	st.Finalise(true)
+	if st.Exist(empty) {
+		t.Errorf("touched empty account not deleted")
+	}
+	if !st.Exist(full) {
+		t.Errorf("non-empty account deleted")
+	}
+}
+
+func TestTransientStorage(t *testing.T) {
+	var (
+		st   = NewAccountStateDb()
+		addr = common.HexToAddress("0xaaaa")
+		key  = common.HexToHash("0x01")
+		val  = common.HexToHash("0x11")
+	)
+	snap := st.Snapshot()
+	st.SetTransientState(addr, key, val)
+	if have := st.GetTransientState(addr, key); have != val {
+		t.Fatalf("transient value mismatch: have %x, want %x", have, val)
+	}
+	if st.Exist(addr) {
+		t.Errorf("transient write created an account")
+	}
+	st.RevertToSnapshot(snap)
+	if have := st.GetTransientState(addr, key); have != (common.Hash{}) {
+		t.Fatalf("transient value survived revert: %x", have)
+	}
+
+	st.SetTransientState(addr, key, val)
+	st.Prepare(params.Rules{}, common.Address{}, common.Address{}, nil, nil, nil)
+	if have := st.GetTransientState(addr, key); have != (common.Hash{}) {
+		t.Fatalf("transient value survived Prepare: %x", have)
+	}
+}
+
+func TestSelfDestruct(t *testing.T) {
+	var (
+		st   = NewAccountStateDb()
+		addr = common.HexToAddress("0xaaaa")
+	)
+	st.AddBalance(addr, uint256.NewInt(10), tracing.BalanceChangeUnspecified)
+	st.SetCode(addr, []byte{0x00})
+
+	snap := st.Snapshot()
+	st.SelfDestruct(addr)
+	if !st.HasSelfDestructed(addr) || !st.GetBalance(addr).IsZero() {
+		t.Fatalf("account not marked as self-destructed")
+	}
+	if !st.Exist(addr) {
+		t.Fatalf("self-destructed account must exist until finalise")
+	}
+	st.RevertToSnapshot(snap)
+	if st.HasSelfDestructed(addr) || st.GetBalance(addr).Uint64() != 10 {
+		t.Fatalf("self-destruct not reverted")
+	}
+
+	st.SelfDestruct(addr)
+	st.Finalise(false)
+	if st.Exist(addr) {
+		t.Fatalf("self-destructed account survived finalise")
+	}
+}
+
+func TestSelfdestruct6780(t *testing.T) {
+	var (
+		st      = NewAccountStateDb()
+		old     = common.HexToAddress("0xaaaa")
+		created = common.HexToAddress("0xbbbb")
+	)
+	st.SetCode(old, []byte{0x00})
+	st.Finalise(false)
+
+	st.CreateAccount(created)
+	st.SetCode(created, []byte{0x00})
+	st.Selfdestruct6780(old)
+	st.Selfdestruct6780(created)
+	if st.HasSelfDestructed(old) {
+		t.Errorf("pre-existing contract destructed under EIP-6780")
+	}
+	if !st.HasSelfDestructed(created) {
+		t.Errorf("contract created in the same transaction not destructed")
+	}
+	st.Finalise(false)
+	if !st.Exist(old) || st.Exist(created) {
+		t.Errorf("unexpected accounts after finalise: old %v created %v", st.Exist(old), st.Exist(created))
+	}
+}
+
+func TestLogsAndReceipt(t *testing.T) {
+	var (
+		st     = NewAccountStateDb()
+		addr   = common.HexToAddress("0xaaaa")
+		topic  = common.HexToHash("0xdead")
+		txHash = common.HexToHash("0x1234")
+		block  = common.HexToHash("0xb10c")
+	)
+	st.SetTxContext(common.HexToHash("0x01"), 0)
+	st.AddLog(&types.Log{Address: addr})
+
+	st.SetTxContext(txHash, 1)
+	st.AddLog(&types.Log{Address: addr, Topics: []common.Hash{topic}})
+	snap := st.Snapshot()
+	st.AddLog(&types.Log{Address: addr})
+	st.RevertToSnapshot(snap)
+
+	receipt := st.MakeReceipt(types.DynamicFeeTxType, false, 21000, 42000, big.NewInt(7), block)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Errorf("status mismatch: have %d", receipt.Status)
+	}
+	if receipt.TxHash != txHash || receipt.TransactionIndex != 1 || receipt.GasUsed != 21000 || receipt.CumulativeGasUsed != 42000 {
+		t.Errorf("receipt fields mismatch: %+v", receipt)
+	}
+	if len(receipt.Logs) != 1 {
+		t.Fatalf("log count mismatch: have %d, want 1", len(receipt.Logs))
+	}
+	log := receipt.Logs[0]
+	if log.TxHash != txHash || log.TxIndex != 1 || log.Index != 1 || log.BlockNumber != 7 || log.BlockHash != block {
+		t.Errorf("log fields mismatch: %+v", log)
+	}
+	if !types.BloomLookup(receipt.Bloom, topic) || !types.BloomLookup(receipt.Bloom, addr) {
+		t.Errorf("bloom is missing log entries")
+	}
+	if have := len(st.Logs()); have != 2 {
+		t.Errorf("total log count mismatch: have %d, want 2", have)
+	}
+}
+
+func TestIntermediateRoot(t *testing.T) {
+	if root := NewAccountStateDb().IntermediateRoot(false); root != types.EmptyRootHash {
+		t.Fatalf("empty state root mismatch: have %x, want %x", root, types.EmptyRootHash)
+	}
+	build := func(order []int) *StateDB {
+		st := NewAccountStateDb()
+		for _, i := range order {
+			addr := common.BytesToAddress([]byte{byte(i)})
+			st.AddBalance(addr, uint256.NewInt(uint64(i+1)), tracing.BalanceChangeUnspecified)
+			st.SetNonce(addr, uint64(i))
+			st.SetState(addr, common.Hash{byte(i)}, common.Hash{31: byte(i + 1)})
+		}
+		return st
+	}
+	a, b := build([]int{0, 1, 2, 3}), build([]int{3, 1, 0, 2})
+	rootA, rootB := a.IntermediateRoot(false), b.IntermediateRoot(false)
+	if rootA != rootB {
+		t.Fatalf("root depends on insertion order: %x != %x", rootA, rootB)
+	}
+	addr := common.BytesToAddress([]byte{1})
+	if have := a.GetStorageRoot(addr); have == types.EmptyRootHash || have == (common.Hash{}) {
+		t.Errorf("storage root of non-empty storage is empty: %x", have)
+	}
+
+	snap := b.Snapshot()
+	b.SetState(addr, common.Hash{}, common.Hash{31: 0xff})
+	b.RevertToSnapshot(snap)
+	if root := b.IntermediateRoot(false); root != rootA {
+		t.Errorf("reverted change altered the root: %x != %x", root, rootA)
+	}
+	b.SetState(addr, common.Hash{}, common.Hash{31: 0xff})
+	if root := b.IntermediateRoot(false); root == rootA {
+		t.Errorf("storage change did not alter the root")
+	}
+}
+
+// TestIntermediateRootGethCompat checks the state root against the value
+// go-ethereum computes for the same set of accounts.
+func TestIntermediateRootGethCompat(t *testing.T) {
+	st := NewAccountStateDb()
+	for i := 0; i < 4; i++ {
+		addr := common.BytesToAddress([]byte{byte(i)})
+		st.AddBalance(addr, uint256.NewInt(uint64(i+1)), tracing.BalanceChangeUnspecified)
+		st.SetNonce(addr, uint64(i))
+		st.SetState(addr, common.Hash{byte(i)}, common.Hash{31: byte(i + 1)})
+	}
+	st.SetCode(common.BytesToAddress([]byte{9}), []byte{0x60, 0x00})
+
+	exp := common.HexToHash("22024019194261b5c17315a6ee0ea6a92f8d0b00389bf0e493c8f3b32d68fde4")
+	if root := st.IntermediateRoot(false); root != exp {
+		t.Errorf("state root mismatch: have %x, want %x", root, exp)
+	}
+}
+
+func TestCommitAndReopen(t *testing.T) {
+	var (
+		db    = NewDatabase(rawdb.NewMemoryDatabase())
+		addr  = common.BytesToAddress([]byte{1})
+		gone  = common.BytesToAddress([]byte{2})
+		code  = []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+		key   = common.Hash{1}
+		value = common.Hash{31: 0x2a}
+	)
+	st, _ := New(types.EmptyRootHash, db)
+	st.AddBalance(addr, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	st.SetNonce(addr, 3)
+	st.SetCode(addr, code)
+	st.SetState(addr, key, value)
+	st.SetState(addr, common.Hash{2}, value)
+	st.AddBalance(gone, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	root, err := st.Commit(false)
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if have := rawdb.ReadHeadStateRoot(db.DiskDB()); have != root {
+		t.Errorf("head root mismatch: have %x, want %x", have, root)
+	}
+
+	reopened, err := New(root, db)
+	if err != nil {
+		t.Fatalf("can't reopen state: %v", err)
+	}
+	if have := reopened.GetBalance(addr); have.Uint64() != 100 {
+		t.Errorf("balance mismatch: have %v, want 100", have)
+	}
+	if have := reopened.GetNonce(addr); have != 3 {
+		t.Errorf("nonce mismatch: have %d, want 3", have)
+	}
+	if have := reopened.GetCode(addr); !bytes.Equal(have, code) {
+		t.Errorf("code mismatch: have %x, want %x", have, code)
+	}
+	if have := reopened.GetCommittedState(addr, key); have != value {
+		t.Errorf("storage mismatch: have %x, want %x", have, value)
+	}
+	if !reopened.Exist(gone) {
+		t.Errorf("account %x missing after reopen", gone)
+	}
+
+	// Modify the reopened state, the second commit must produce the same root
+	// as an in-memory state with the same content.
+	reopened.SetState(addr, common.Hash{2}, common.Hash{})
+	reopened.SelfDestruct(gone)
+	root2, err := reopened.Commit(false)
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	exp := NewAccountStateDb()
+	exp.AddBalance(addr, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	exp.SetNonce(addr, 3)
+	exp.SetCode(addr, code)
+	exp.SetState(addr, key, value)
+	if want := exp.IntermediateRoot(false); root2 != want {
+		t.Errorf("root mismatch: have %x, want %x", root2, want)
+	}
+	head, err := NewFromHead(db)
+	if err != nil {
+		t.Fatalf("can't open head state: %v", err)
+	}
+	if head.Exist(gone) {
+		t.Errorf("self-destructed account %x still exists", gone)
+	}
+	if have := head.GetState(addr, common.Hash{2}); have != (common.Hash{}) {
+		t.Errorf("cleared slot still set: %x", have)
+	}
+	if err := head.Error(); err != nil {
+		t.Errorf("unexpected database error: %v", err)
+	}
+}
+
+func TestOpenPath(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		addr = common.BytesToAddress([]byte{1})
+	)
+	st, err := Open(dir)
+	if err != nil {
+		t.Fatalf("can't open state: %v", err)
+	}
+	st.AddBalance(addr, uint256.NewInt(7), tracing.BalanceChangeUnspecified)
+	root, err := st.Commit(false)
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	st.Database().DiskDB().Close()
+
+	st, err = Open(dir)
+	if err != nil {
+		t.Fatalf("can't reopen state: %v", err)
+	}
+	defer st.Database().DiskDB().Close()
+	if have := st.IntermediateRoot(false); have != root {
+		t.Errorf("root mismatch: have %x, want %x", have, root)
+	}
+	if have := st.GetBalance(addr); have.Uint64() != 7 {
+		t.Errorf("balance mismatch: have %v, want 7", have)
+	}
+}
+
+func TestStateHistory(t *testing.T) {
+	var (
+		db    = NewDatabase(rawdb.NewMemoryDatabase())
+		addr  = common.BytesToAddress([]byte{1})
+		roots []common.Hash
+	)
+	st, _ := NewFromHead(db)
+	for i := 1; i <= 3; i++ {
+		st.AddBalance(addr, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+		root, err := st.Commit(false)
+		if err != nil {
+			t.Fatalf("commit %d failed: %v", i, err)
+		}
+		roots = append(roots, root)
+	}
+	if have := History(db); len(have) != 3 || have[0] != roots[0] || have[2] != roots[2] {
+		t.Fatalf("history mismatch: have %x, want %x", have, roots)
+	}
+	// Every committed version can be opened, only the head one can be committed.
+	for i, root := range roots {
+		old, err := New(root, db)
+		if err != nil {
+			t.Fatalf("can't open version %d: %v", i, err)
+		}
+		if have := old.GetBalance(addr); have.Uint64() != uint64(i+1) {
+			t.Errorf("version %d balance mismatch: have %v, want %d", i, have, i+1)
+		}
+		if readOnly := old.ReadOnly(); readOnly != (i != 2) {
+			t.Errorf("version %d read-only mismatch: have %v", i, readOnly)
+		}
+	}
+	// A historical state can be modified in memory, e.g. to execute calls on
+	// it, but the modifications never reach the database.
+	old, _ := New(roots[0], db)
+	old.AddBalance(addr, uint256.NewInt(10), tracing.BalanceChangeUnspecified)
+	old.SetCode(addr, []byte{0x01})
+	if have := old.GetBalance(addr); have.Uint64() != 11 {
+		t.Errorf("historical state modification lost: have %v, want 11", have)
+	}
+	if root := old.IntermediateRoot(false); root == roots[0] {
+		t.Errorf("historical state root not updated")
+	}
+	if _, err := old.Commit(false); err != ErrHistoricalState {
+		t.Fatalf("commit of historical state: have %v, want %v", err, ErrHistoricalState)
+	}
+	if reopened, _ := New(roots[0], db); reopened.GetBalance(addr).Uint64() != 1 || reopened.GetCodeSize(addr) != 0 {
+		t.Errorf("historical state modified on disk")
+	}
+	if have := History(db); len(have) != 3 || have[2] != roots[2] {
+		t.Errorf("history modified by historical state: have %x", have)
+	}
+
+	// Roll back to the first version, the state opened at the old head
+	// becomes read-only and the history continues from the first version.
+	if err := Rollback(db, common.Hash{1}); err == nil {
+		t.Errorf("rollback to unknown root succeeded")
+	}
+	if err := Rollback(db, roots[0]); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if !st.ReadOnly() {
+		t.Errorf("state at the old head is still writable")
+	}
+	head, _ := NewFromHead(db)
+	if have := head.GetBalance(addr); have.Uint64() != 1 {
+		t.Errorf("balance after rollback mismatch: have %v, want 1", have)
+	}
+	head.AddBalance(addr, uint256.NewInt(5), tracing.BalanceChangeUnspecified)
+	root, err := head.Commit(false)
+	if err != nil {
+		t.Fatalf("commit after rollback failed: %v", err)
+	}
+	if have := History(db); len(have) != 2 || have[0] != roots[0] || have[1] != root {
+		t.Errorf("history after rollback mismatch: have %x", have)
+	}
+}
@@ -0,0 +1,276 @@
+package mvm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/trie"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testLogger  = common.HexToAddress("0x10c")
+	loggerCode  = common.FromHex("60006000a000") // log0(0, 0)
+	testGasCap  = big.NewInt(params.InitialBaseFee * 2)
+	testTipCap  = big.NewInt(2)
+	testChainID = params.MergedTestChainConfig.ChainID
+)
+
+// newProcessorState returns the pre-state shared by the processor tests.
+func newProcessorState() *state.StateDB {
+	statedb := state.NewAccountStateDb()
+	statedb.AddBalance(testAddr, uint256.NewInt(params.InitialBaseFee*10_000_000), tracing.BalanceChangeUnspecified)
+	statedb.SetCode(testLogger, loggerCode)
+	statedb.IntermediateRoot(true)
+	return statedb
+}
+
+func signDynamicTx(key *ecdsa.PrivateKey, nonce uint64, to *common.Address, gas uint64, data []byte) *types.Transaction {
+	return types.MustSignNewTx(key, types.LatestSigner(params.MergedTestChainConfig), &types.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     nonce,
+		GasTipCap: testTipCap,
+		GasFeeCap: testGasCap,
+		Gas:       gas,
+		To:        to,
+		Value:     big.NewInt(1),
+		Data:      data,
+	})
+}
+
+func TestStateProcessor(t *testing.T) {
+	var (
+		config = params.MergedTestChainConfig
+		to     = common.HexToAddress("0xdead")
+		txs    = types.Transactions{
+			signDynamicTx(testKey, 0, &to, params.TxGas, nil),
+			signDynamicTx(testKey, 1, &testLogger, 50000, nil),
+			signDynamicTx(testKey, 2, nil, 100000, loggerCode),
+		}
+		excessBlobGas = uint64(0)
+		blobGasUsed   = uint64(0)
+		header        = &types.Header{
+			Number:        big.NewInt(1),
+			Difficulty:    new(big.Int),
+			GasLimit:      30_000_000,
+			Coinbase:      testCoinbase,
+			BaseFee:       testBaseFee,
+			ExcessBlobGas: &excessBlobGas,
+			BlobGasUsed:   &blobGasUsed,
+		}
+		processor = NewStateProcessor(config, nil)
+	)
+	// Execute the transactions once to learn the post-state, then seal a block
+	// with the derived header fields and process it again from scratch.
+	res, err := processor.Process(types.NewBlock(header, txs, nil, nil, trie.NewEmpty()), newProcessorState(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	header.Root = res.Root
+	header.GasUsed = res.GasUsed
+	block := types.NewBlock(header, txs, nil, res.Receipts, trie.NewEmpty())
+
+	statedb := newProcessorState()
+	res, err = processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if err := ValidateState(block.Header(), res); err != nil {
+		t.Fatalf("failed to validate state: %v", err)
+	}
+	if len(res.Receipts) != len(txs) || len(res.Logs) != 2 {
+		t.Fatalf("result mismatch: %d receipts, %d logs", len(res.Receipts), len(res.Logs))
+	}
+	var cumulative uint64
+	for i, receipt := range res.Receipts {
+		cumulative += receipt.GasUsed
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			t.Errorf("receipt %d: failed status", i)
+		}
+		if receipt.CumulativeGasUsed != cumulative {
+			t.Errorf("receipt %d: cumulative gas mismatch: have %d, want %d", i, receipt.CumulativeGasUsed, cumulative)
+		}
+		if receipt.TxHash != txs[i].Hash() || receipt.BlockHash != block.Hash() || receipt.TransactionIndex != uint(i) {
+			t.Errorf("receipt %d: position fields mismatch", i)
+		}
+	}
+	if res.GasUsed != cumulative || res.Receipts[0].GasUsed != params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", res.GasUsed, cumulative)
+	}
+	if !res.Receipts[1].Bloom.Test(testLogger.Bytes()) || res.Logs[0].Address != testLogger {
+		t.Errorf("log of the call not collected")
+	}
+	if want := crypto.CreateAddress(testAddr, 2); res.Receipts[2].ContractAddress != want || res.Logs[1].Address != want {
+		t.Errorf("contract address mismatch: have %x, want %x", res.Receipts[2].ContractAddress, want)
+	}
+	if have := statedb.GetNonce(testAddr); have != 3 {
+		t.Errorf("nonce mismatch: have %d, want 3", have)
+	}
+}
+
+func TestStateProcessorSystemOperations(t *testing.T) {
+	var (
+		config     = params.MergedTestChainConfig
+		recipient  = common.HexToAddress("0xdead")
+		beaconRoot = common.HexToHash("0xbeac")
+		header     = &types.Header{
+			Number:           big.NewInt(1),
+			Difficulty:       new(big.Int),
+			GasLimit:         30_000_000,
+			BaseFee:          testBaseFee,
+			ParentBeaconRoot: &beaconRoot,
+		}
+		withdrawals = []*types.Withdrawal{
+			{Index: 0, Validator: 1, Address: recipient, Amount: 2},
+			{Index: 1, Validator: 2, Address: recipient, Amount: 3},
+		}
+		statedb = newProcessorState()
+	)
+	// A stand-in for the beacon roots contract storing the root in slot zero.
+	statedb.SetCode(params.BeaconRootsAddress, common.FromHex("600035600055")) // sstore(0, calldataload(0))
+	statedb.IntermediateRoot(true)
+
+	block := types.NewBlockWithWithdrawals(header, nil, nil, nil, withdrawals, trie.NewEmpty())
+	if _, err := NewStateProcessor(config, nil).Process(block, statedb, vm.Config{}); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if have, want := statedb.GetBalance(recipient), uint256.NewInt(5*params.GWei); have.Cmp(want) != 0 {
+		t.Errorf("withdrawal balance mismatch: have %v, want %v", have, want)
+	}
+	if have := statedb.GetState(params.BeaconRootsAddress, common.Hash{}); have != beaconRoot {
+		t.Errorf("beacon root mismatch: have %x, want %x", have, beaconRoot)
+	}
+}
+
+// TestStateProcessorHooks checks that the state hooks of the tracer fire for
+// the state modifications done while processing a block.
+func TestStateProcessorHooks(t *testing.T) {
+	var (
+		config  = params.MergedTestChainConfig
+		to      = common.HexToAddress("0xdead")
+		storer  = common.HexToAddress("0x5707e")
+		header  = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), GasLimit: 30_000_000, BaseFee: testBaseFee}
+		statedb = newProcessorState()
+		txs     = types.Transactions{
+			signDynamicTx(testKey, 0, &to, params.TxGas, nil),
+			signDynamicTx(testKey, 1, &testLogger, 50000, nil),
+			signDynamicTx(testKey, 2, &storer, 50000, nil),
+		}
+		balances, nonces, slots, logs, txEnds int
+	)
+	statedb.SetCode(storer, common.FromHex("6001600055")) // sstore(0, 1)
+	statedb.IntermediateRoot(true)
+
+	hooks := &tracing.Hooks{
+		OnTxStart: func(*tracing.VMContext, *types.Transaction, common.Address) {},
+		OnTxEnd:   func(*types.Receipt, error) { txEnds++ },
+		OnBalanceChange: func(addr common.Address, prev, new *big.Int, _ tracing.BalanceChangeReason) {
+			if statedb.GetBalance(addr).ToBig().Cmp(prev) != 0 {
+				t.Errorf("balance hook of %x fired after the change", addr)
+			}
+			balances++
+		},
+		OnNonceChange: func(addr common.Address, prev, _ uint64) {
+			if statedb.GetNonce(addr) != prev {
+				t.Errorf("nonce hook of %x fired after the change", addr)
+			}
+			nonces++
+		},
+		OnStorageChange: func(addr common.Address, slot, prev, new common.Hash) {
+			if addr != storer || prev != (common.Hash{}) || new != common.BytesToHash([]byte{1}) {
+				t.Errorf("unexpected storage change %x[%x]: %x -> %x", addr, slot, prev, new)
+			}
+			slots++
+		},
+		OnLog: func(l *types.Log) {
+			if l.Address != testLogger || l.TxHash != txs[1].Hash() {
+				t.Errorf("unexpected log: %+v", l)
+			}
+			logs++
+		},
+	}
+	if _, err := NewStateProcessor(config, nil).Process(types.NewBlock(header, txs, nil, nil, trie.NewEmpty()), statedb, vm.Config{Tracer: hooks}); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if txEnds != len(txs) || nonces != len(txs) || slots != 1 || logs != 1 {
+		t.Errorf("hook count mismatch: %d tx ends, %d nonces, %d slots, %d logs", txEnds, nonces, slots, logs)
+	}
+	// Every transaction buys gas, refunds the rest and pays the tip, the
+	// transfer also credits the recipient.
+	if balances < 3*len(txs)+1 {
+		t.Errorf("too few balance changes: %d", balances)
+	}
+}
+
+func TestValidateState(t *testing.T) {
+	var (
+		config = params.MergedTestChainConfig
+		to     = common.HexToAddress("0xdead")
+		txs    = types.Transactions{signDynamicTx(testKey, 0, &testLogger, 50000, nil), signDynamicTx(testKey, 1, &to, params.TxGas, nil)}
+		header = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), GasLimit: 30_000_000, BaseFee: testBaseFee}
+	)
+	res, err := NewStateProcessor(config, nil).Process(types.NewBlock(header, txs, nil, nil, trie.NewEmpty()), newProcessorState(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	header.Root = res.Root
+	header.GasUsed = res.GasUsed
+	valid := types.NewBlock(header, txs, nil, res.Receipts, trie.NewEmpty()).Header()
+	if err := ValidateState(valid, res); err != nil {
+		t.Fatalf("failed to validate state: %v", err)
+	}
+	tests := []struct {
+		modify func(*types.Header)
+		want   string
+	}{
+		{func(h *types.Header) { h.GasUsed++ }, "invalid gas used"},
+		{func(h *types.Header) { h.Bloom = types.Bloom{} }, "invalid bloom"},
+		{func(h *types.Header) { h.ReceiptHash = types.EmptyReceiptsHash }, "invalid receipt root hash"},
+		{func(h *types.Header) { h.Root = types.EmptyRootHash }, "invalid merkle root"},
+		{func(h *types.Header) { h.BlobGasUsed = new(uint64); *h.BlobGasUsed = 1 }, "blob gas used mismatch"},
+	}
+	for i, tt := range tests {
+		header := types.CopyHeader(valid)
+		tt.modify(header)
+		if err := ValidateState(header, res); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.want)
+		}
+	}
+}
+
+func TestStateProcessorErrors(t *testing.T) {
+	var (
+		config = params.MergedTestChainConfig
+		to     = common.HexToAddress("0xdead")
+		header = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), GasLimit: 30_000_000, BaseFee: testBaseFee}
+	)
+	tests := []struct {
+		txs  types.Transactions
+		gas  uint64
+		want error
+	}{
+		{types.Transactions{signDynamicTx(testKey, 1, &to, params.TxGas, nil)}, 30_000_000, ErrNonceTooHigh},
+		{types.Transactions{signDynamicTx(testKey, 0, &to, params.TxGas, nil), signDynamicTx(testKey, 0, &to, params.TxGas, nil)}, 30_000_000, ErrNonceTooLow},
+		{types.Transactions{signDynamicTx(testKey, 0, &to, params.TxGas, nil), signDynamicTx(testKey, 1, &to, params.TxGas, nil)}, params.TxGas, ErrGasLimitReached},
+	}
+	for i, tt := range tests {
+		header := types.CopyHeader(header)
+		header.GasLimit = tt.gas
+		block := types.NewBlock(header, tt.txs, nil, nil, trie.NewEmpty())
+		if _, err := NewStateProcessor(config, nil).Process(block, newProcessorState(), vm.Config{}); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
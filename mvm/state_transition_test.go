@@ -0,0 +1,335 @@
+package mvm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+var (
+	testSender   = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	testCoinbase = common.HexToAddress("0xc014ba5e")
+	testBaseFee  = big.NewInt(params.InitialBaseFee)
+)
+
+// newTestEVM returns an evm on a Cancun block with the given state.
+func newTestEVM(statedb *state.StateDB, msg *Message) *vm.EVM {
+	excessBlobGas := uint64(0)
+	header := &types.Header{
+		Number:        big.NewInt(1),
+		Difficulty:    new(big.Int),
+		GasLimit:      30_000_000,
+		Coinbase:      testCoinbase,
+		BaseFee:       testBaseFee,
+		ExcessBlobGas: &excessBlobGas,
+	}
+	return vm.NewEVM(NewEVMBlockContext(header, nil), NewEVMTxContext(msg), statedb, params.MergedTestChainConfig, vm.Config{})
+}
+
+// newTestMessage returns a dynamic fee message from testSender paying the base
+// fee plus a tip of 2 wei.
+func newTestMessage(to *common.Address, nonce uint64, gas uint64, data []byte) *Message {
+	feeCap := new(big.Int).Add(testBaseFee, big.NewInt(5))
+	return &Message{
+		To:        to,
+		From:      testSender,
+		Nonce:     nonce,
+		Value:     big.NewInt(1000),
+		GasLimit:  gas,
+		GasPrice:  new(big.Int).Add(testBaseFee, big.NewInt(2)),
+		GasFeeCap: feeCap,
+		GasTipCap: big.NewInt(2),
+		Data:      data,
+	}
+}
+
+func TestApplyMessageTransfer(t *testing.T) {
+	var (
+		statedb = state.NewAccountStateDb()
+		to      = common.HexToAddress("0xdead")
+		msg     = newTestMessage(&to, 0, 50000, nil)
+		initial = uint256.NewInt(params.InitialBaseFee * 100000)
+	)
+	statedb.AddBalance(testSender, initial, tracing.BalanceChangeUnspecified)
+
+	gp := new(GasPool).AddGas(30_000_000)
+	result, err := ApplyMessage(newTestEVM(statedb, msg), msg, gp)
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() || result.UsedGas != params.TxGas {
+		t.Fatalf("unexpected result: used %d, err %v", result.UsedGas, result.Err)
+	}
+	if have := gp.Gas(); have != 30_000_000-params.TxGas {
+		t.Errorf("gas pool mismatch: have %d, want %d", have, 30_000_000-params.TxGas)
+	}
+	if have := statedb.GetNonce(testSender); have != 1 {
+		t.Errorf("nonce mismatch: have %d, want 1", have)
+	}
+	// The sender pays the effective gas price for the used gas plus the value,
+	// the coinbase only receives the tip.
+	cost := new(big.Int).Mul(msg.GasPrice, new(big.Int).SetUint64(params.TxGas))
+	cost.Add(cost, msg.Value)
+	want := new(big.Int).Sub(initial.ToBig(), cost)
+	if have := statedb.GetBalance(testSender).ToBig(); have.Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", have, want)
+	}
+	if have := statedb.GetBalance(to).Uint64(); have != 1000 {
+		t.Errorf("recipient balance mismatch: have %d, want 1000", have)
+	}
+	if have := statedb.GetBalance(testCoinbase).Uint64(); have != 2*params.TxGas {
+		t.Errorf("coinbase balance mismatch: have %d, want %d", have, 2*params.TxGas)
+	}
+}
+
+func TestApplyMessageRevert(t *testing.T) {
+	var (
+		statedb = state.NewAccountStateDb()
+		to      = common.HexToAddress("0xc0de")
+		// mstore(0, 42) revert(0, 32)
+		code = common.FromHex("602a60005260206000fd")
+		msg  = newTestMessage(&to, 0, 100000, nil)
+	)
+	statedb.AddBalance(testSender, uint256.NewInt(params.InitialBaseFee*200000), tracing.BalanceChangeUnspecified)
+	statedb.SetCode(to, code)
+
+	result, err := ApplyMessage(newTestEVM(statedb, msg), msg, new(GasPool).AddGas(30_000_000))
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if !errors.Is(result.Err, vm.ErrExecutionReverted) {
+		t.Fatalf("execution error mismatch: have %v, want %v", result.Err, vm.ErrExecutionReverted)
+	}
+	if !bytes.Equal(result.Revert(), common.LeftPadBytes([]byte{42}, 32)) {
+		t.Errorf("revert data mismatch: %x", result.Revert())
+	}
+	if result.Return() != nil {
+		t.Errorf("failed execution returned data: %x", result.Return())
+	}
+	// The nonce is still increased and the value stays with the sender.
+	if have := statedb.GetNonce(testSender); have != 1 {
+		t.Errorf("nonce mismatch: have %d, want 1", have)
+	}
+	if have := statedb.GetBalance(to); !have.IsZero() {
+		t.Errorf("value transferred by reverted call: %v", have)
+	}
+}
+
+func TestApplyMessagePreCheck(t *testing.T) {
+	to := common.HexToAddress("0xdead")
+	tests := []struct {
+		name   string
+		modify func(*Message)
+		want   error
+	}{
+		{"nonce too low", func(m *Message) { m.Nonce = 0 }, ErrNonceTooLow},
+		{"nonce too high", func(m *Message) { m.Nonce = 5 }, ErrNonceTooHigh},
+		{"insufficient funds", func(m *Message) { m.Value = big.NewInt(params.InitialBaseFee * 100000) }, ErrInsufficientFunds},
+		{"intrinsic gas", func(m *Message) { m.GasLimit = params.TxGas - 1 }, ErrIntrinsicGas},
+		{"block gas limit", func(m *Message) {}, ErrGasLimitReached},
+		{"fee cap too low", func(m *Message) { m.GasFeeCap = big.NewInt(1); m.GasTipCap = big.NewInt(1) }, ErrFeeCapTooLow},
+		{"tip above fee cap", func(m *Message) { m.GasTipCap = new(big.Int).Add(m.GasFeeCap, common.Big1) }, ErrTipAboveFeeCap},
+		{"blob create", func(m *Message) { m.To = nil; m.BlobHashes = []common.Hash{{0x01}} }, ErrBlobTxCreate},
+		{"missing blob hashes", func(m *Message) { m.BlobHashes = []common.Hash{} }, ErrMissingBlobHashes},
+		{"blob fee cap", func(m *Message) { m.BlobHashes = []common.Hash{{0x01}}; m.BlobGasFeeCap = new(big.Int) }, ErrBlobFeeCapTooLow},
+	}
+	for _, tt := range tests {
+		statedb := state.NewAccountStateDb()
+		statedb.AddBalance(testSender, uint256.NewInt(params.InitialBaseFee*100000), tracing.BalanceChangeUnspecified)
+		statedb.SetNonce(testSender, 1)
+
+		msg := newTestMessage(&to, 1, 50000, nil)
+		tt.modify(msg)
+		evm := newTestEVM(statedb, msg)
+		if tt.want == ErrBlobFeeCapTooLow {
+			evm.Context.BlobBaseFee = big.NewInt(10)
+		}
+		gp := new(GasPool).AddGas(30_000_000)
+		if tt.want == ErrGasLimitReached {
+			gp.SetGas(msg.GasLimit - 1)
+		}
+		_, err := ApplyMessage(evm, msg, gp)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestApplyMessageSkipAccountChecks(t *testing.T) {
+	var (
+		statedb = state.NewAccountStateDb()
+		to      = common.HexToAddress("0xdead")
+		msg     = newTestMessage(&to, 7, 50000, nil)
+	)
+	statedb.AddBalance(testSender, uint256.NewInt(params.InitialBaseFee*100000), tracing.BalanceChangeUnspecified)
+	statedb.SetCode(testSender, []byte{0x00})
+
+	if _, err := ApplyMessage(newTestEVM(statedb, msg), msg, new(GasPool).AddGas(30_000_000)); !errors.Is(err, ErrNonceTooHigh) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+	msg.Nonce = 0
+	if _, err := ApplyMessage(newTestEVM(statedb, msg), msg, new(GasPool).AddGas(30_000_000)); !errors.Is(err, ErrSenderNoEOA) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrSenderNoEOA)
+	}
+	msg.Nonce = 7
+	msg.SkipAccountChecks = true
+	result, err := ApplyMessage(newTestEVM(statedb, msg), msg, new(GasPool).AddGas(30_000_000))
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	data := []byte{0, 1, 0, 2}
+	tests := []struct {
+		create, homestead, eip2028, eip3860 bool
+		want                                uint64
+	}{
+		{false, true, true, true, params.TxGas + 2*params.TxDataZeroGas + 2*params.TxDataNonZeroGasEIP2028},
+		{false, true, false, false, params.TxGas + 2*params.TxDataZeroGas + 2*params.TxDataNonZeroGasFrontier},
+		{true, false, true, false, params.TxGas + 2*params.TxDataZeroGas + 2*params.TxDataNonZeroGasEIP2028},
+		{true, true, true, true, params.TxGasContractCreation + 2*params.TxDataZeroGas + 2*params.TxDataNonZeroGasEIP2028 + params.InitCodeWordGas},
+	}
+	for i, tt := range tests {
+		have, err := IntrinsicGas(data, nil, tt.create, tt.homestead, tt.eip2028, tt.eip3860)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if have != tt.want {
+			t.Errorf("test %d: intrinsic gas mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	accessList := types.AccessList{{Address: common.Address{1}, StorageKeys: []common.Hash{{1}, {2}}}}
+	have, _ := IntrinsicGas(nil, accessList, false, true, true, true)
+	if want := params.TxGas + params.TxAccessListAddressGas + 2*params.TxAccessListStorageKeyGas; have != want {
+		t.Errorf("access list intrinsic gas mismatch: have %d, want %d", have, want)
+	}
+}
+
+func TestTransactionToMessage(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		from       = crypto.PubkeyToAddress(key.PublicKey)
+		to         = common.HexToAddress("0xdead")
+		config     = params.MergedTestChainConfig
+		signer     = types.MakeSigner(config, big.NewInt(1), 0)
+		accessList = types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}
+		blobHashes = []common.Hash{{0x01, 0x02}}
+	)
+	tests := []struct {
+		name  string
+		tx    types.TxData
+		price *big.Int // effective gas price under testBaseFee
+	}{
+		{
+			name:  "legacy",
+			tx:    &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(params.InitialBaseFee * 2), Gas: 21000, To: &to, Value: big.NewInt(1)},
+			price: big.NewInt(params.InitialBaseFee * 2),
+		},
+		{
+			name: "dynamic fee tip",
+			tx: &types.DynamicFeeTx{ChainID: config.ChainID, Nonce: 2, GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(params.InitialBaseFee * 2),
+				Gas: 21000, To: &to, AccessList: accessList},
+			price: new(big.Int).Add(testBaseFee, big.NewInt(2)),
+		},
+		{
+			name: "dynamic fee capped",
+			tx: &types.DynamicFeeTx{ChainID: config.ChainID, Nonce: 3, GasTipCap: big.NewInt(100), GasFeeCap: new(big.Int).Add(testBaseFee, big.NewInt(5)),
+				Gas: 21000, To: &to},
+			price: new(big.Int).Add(testBaseFee, big.NewInt(5)),
+		},
+		{
+			name: "blob",
+			tx: &types.BlobTx{ChainID: uint256.MustFromBig(config.ChainID), Nonce: 4, GasTipCap: uint256.NewInt(2), GasFeeCap: uint256.NewInt(params.InitialBaseFee * 2),
+				Gas: 21000, To: to, AccessList: accessList, BlobFeeCap: uint256.NewInt(7), BlobHashes: blobHashes},
+			price: new(big.Int).Add(testBaseFee, big.NewInt(2)),
+		},
+	}
+	for _, tt := range tests {
+		tx := types.MustSignNewTx(key, signer, tt.tx)
+		msg, err := TransactionToMessage(tx, signer, testBaseFee)
+		if err != nil {
+			t.Fatalf("%s: failed to convert transaction: %v", tt.name, err)
+		}
+		if msg.From != from {
+			t.Errorf("%s: sender mismatch: have %x, want %x", tt.name, msg.From, from)
+		}
+		if msg.Nonce != tx.Nonce() || msg.GasLimit != tx.Gas() || *msg.To != to {
+			t.Errorf("%s: message fields mismatch: %+v", tt.name, msg)
+		}
+		if msg.GasPrice.Cmp(tt.price) != 0 {
+			t.Errorf("%s: gas price mismatch: have %v, want %v", tt.name, msg.GasPrice, tt.price)
+		}
+		if msg.GasFeeCap.Cmp(tx.GasFeeCap()) != 0 || msg.GasTipCap.Cmp(tx.GasTipCap()) != 0 {
+			t.Errorf("%s: fee caps mismatch: have %v/%v", tt.name, msg.GasFeeCap, msg.GasTipCap)
+		}
+		if len(msg.AccessList) != len(tx.AccessList()) || len(msg.BlobHashes) != len(tx.BlobHashes()) {
+			t.Errorf("%s: access list or blob hashes not carried over", tt.name)
+		}
+		if tx.Type() == types.BlobTxType && (msg.BlobGasFeeCap.Uint64() != 7 || msg.BlobHashes[0] != blobHashes[0]) {
+			t.Errorf("%s: blob fields mismatch: cap %v, hashes %v", tt.name, msg.BlobGasFeeCap, msg.BlobHashes)
+		}
+	}
+	// Without a base fee the gas price is taken verbatim from the transaction.
+	tx := types.MustSignNewTx(key, signer, tests[1].tx)
+	msg, err := TransactionToMessage(tx, signer, nil)
+	if err != nil {
+		t.Fatalf("failed to convert transaction: %v", err)
+	}
+	if msg.GasPrice.Cmp(tx.GasPrice()) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", msg.GasPrice, tx.GasPrice())
+	}
+	// A signer for another chain can't recover the sender.
+	other := types.LatestSignerForChainID(big.NewInt(12345))
+	if _, err := TransactionToMessage(tx, other, testBaseFee); !errors.Is(err, types.ErrInvalidChainId) {
+		t.Errorf("error mismatch: have %v, want %v", err, types.ErrInvalidChainId)
+	}
+}
+
+func TestApplySignedTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		statedb = state.NewAccountStateDb()
+		to      = common.HexToAddress("0xdead")
+		signer  = types.LatestSigner(params.MergedTestChainConfig)
+		tx      = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   params.MergedTestChainConfig.ChainID,
+			GasTipCap: big.NewInt(2),
+			GasFeeCap: big.NewInt(params.InitialBaseFee * 2),
+			Gas:       21000,
+			To:        &to,
+			Value:     big.NewInt(1000),
+		})
+	)
+	msg, err := TransactionToMessage(tx, signer, testBaseFee)
+	if err != nil {
+		t.Fatalf("failed to convert transaction: %v", err)
+	}
+	statedb.AddBalance(msg.From, uint256.NewInt(params.InitialBaseFee*100000), tracing.BalanceChangeUnspecified)
+	result, err := ApplyMessage(newTestEVM(statedb, msg), msg, new(GasPool).AddGas(30_000_000))
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	if have := statedb.GetNonce(msg.From); have != 1 {
+		t.Errorf("nonce mismatch: have %d, want 1", have)
+	}
+	if have := statedb.GetBalance(testCoinbase).Uint64(); have != 2*params.TxGas {
+		t.Errorf("coinbase balance mismatch: have %d, want %d", have, 2*params.TxGas)
+	}
+}
@@ -0,0 +1,27 @@
+// Package tracetest holds the execution environment shared by the tracer
+// tests.
+package tracetest
+
+import (
+	"math/big"
+
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/mock"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/vm"
+)
+
+var (
+	Caller   = common.HexToAddress("0xc0ffee") // Sender of the traced calls
+	Contract = common.HexToAddress("0xc0de")   // Contract called by Caller
+	Callee   = common.HexToAddress("0xca11ee") // Contract called by Contract
+)
+
+// NewEVM returns an EVM executing on statedb in the first block of the test
+// chain, without gas price, which reports to the given hooks.
+func NewEVM(statedb vm.StateDB, hooks *tracing.Hooks) *vm.EVM {
+	context := mvm.NewEVMBlockContext(mock.GetHeader(1, 1, 1), nil)
+	return vm.NewEVM(context, vm.TxContext{GasPrice: new(big.Int)}, statedb, params.TestChainConfig, vm.Config{Tracer: hooks})
+}
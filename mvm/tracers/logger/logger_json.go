@@ -0,0 +1,182 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/hexutil"
+	"github.com/a1146910248/mixchain/mvm/common/math"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+// callFrame is emitted every call frame entered.
+type callFrame struct {
+	Op    string         `json:"op"`
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Input hexutil.Bytes  `json:"input,omitempty"`
+	Gas   hexutil.Uint64 `json:"gas"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+// endLog is the summary emitted when the outermost call frame returns.
+type endLog struct {
+	Output  string              `json:"output"`
+	GasUsed math.HexOrDecimal64 `json:"gasUsed"`
+	Err     string              `json:"error,omitempty"`
+}
+
+type jsonLogger struct {
+	encoder    *json.Encoder
+	cfg        *Config
+	env        *tracing.VMContext
+	storage    map[common.Address]Storage
+	callFrames bool
+}
+
+// NewJSONLogger creates a new EVM tracer that prints execution steps as JSON objects
+// into the provided stream, one object per line as specified by EIP-3155. The
+// execution ends with a summary line holding the output, the gas used and the
+// error of the outermost call frame.
+func NewJSONLogger(cfg *Config, writer io.Writer) *tracing.Hooks {
+	l := newJSONLogger(cfg, writer)
+	return &tracing.Hooks{
+		OnTxStart: l.OnTxStart,
+		OnExit:    l.OnExit,
+		OnOpcode:  l.OnOpcode,
+		OnFault:   l.OnFault,
+	}
+}
+
+// NewJSONLoggerWithCallFrames creates a new EVM tracer that prints execution steps as JSON objects
+// into the provided stream. It also includes call frames in the output.
+func NewJSONLoggerWithCallFrames(cfg *Config, writer io.Writer) *tracing.Hooks {
+	l := newJSONLogger(cfg, writer)
+	l.callFrames = true
+	return &tracing.Hooks{
+		OnTxStart: l.OnTxStart,
+		OnEnter:   l.OnEnter,
+		OnExit:    l.OnExit,
+		OnOpcode:  l.OnOpcode,
+		OnFault:   l.OnFault,
+	}
+}
+
+func newJSONLogger(cfg *Config, writer io.Writer) *jsonLogger {
+	l := &jsonLogger{encoder: json.NewEncoder(writer), cfg: cfg, storage: make(map[common.Address]Storage)}
+	if l.cfg == nil {
+		l.cfg = &Config{}
+	}
+	return l
+}
+
+func (l *jsonLogger) OnFault(pc uint64, op byte, gas uint64, cost uint64, scope tracing.OpContext, depth int, err error) {
+	// TODO: Add rData to this interface as well
+	l.OnOpcode(pc, op, gas, cost, scope, nil, depth, err)
+}
+
+func (l *jsonLogger) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	memory := scope.MemoryData()
+	stack := scope.StackData()
+
+	log := StructLog{
+		Pc:            pc,
+		Op:            vm.OpCode(op),
+		Gas:           gas,
+		GasCost:       cost,
+		MemorySize:    len(memory),
+		Depth:         depth,
+		RefundCounter: l.env.StateDB.GetRefund(),
+		Err:           err,
+	}
+	if l.cfg.EnableMemory {
+		log.Memory = memory
+	}
+	if !l.cfg.DisableStack {
+		log.Stack = stack
+	}
+	if l.cfg.EnableReturnData {
+		log.ReturnData = rData
+	}
+	if !l.cfg.DisableStorage {
+		log.Storage = l.captureStorage(log.Op, scope.Address(), stack)
+	}
+	l.encoder.Encode(log)
+}
+
+// captureStorage records the slot read by SLOAD or written by SSTORE and
+// returns the slots of the contract touched so far. Other opcodes leave the
+// storage unreported.
+func (l *jsonLogger) captureStorage(op vm.OpCode, contractAddr common.Address, stack []uint256.Int) Storage {
+	var (
+		key, value common.Hash
+		stackLen   = len(stack)
+	)
+	switch {
+	case op == vm.SLOAD && stackLen >= 1:
+		key = common.Hash(stack[stackLen-1].Bytes32())
+		value = l.env.StateDB.GetState(contractAddr, key)
+	case op == vm.SSTORE && stackLen >= 2:
+		key = common.Hash(stack[stackLen-1].Bytes32())
+		value = common.Hash(stack[stackLen-2].Bytes32())
+	default:
+		return nil
+	}
+	if l.storage[contractAddr] == nil {
+		l.storage[contractAddr] = make(Storage)
+	}
+	l.storage[contractAddr][key] = value
+	return l.storage[contractAddr].Copy()
+}
+
+// OnEnter is not enabled by default.
+func (l *jsonLogger) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	frame := callFrame{
+		Op:    vm.OpCode(typ).String(),
+		From:  from,
+		To:    to,
+		Gas:   hexutil.Uint64(gas),
+		Value: (*hexutil.Big)(value),
+	}
+	if l.cfg.EnableMemory {
+		frame.Input = input
+	}
+	l.encoder.Encode(frame)
+}
+
+func (l *jsonLogger) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if depth != 0 && !l.callFrames {
+		return
+	}
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+	l.encoder.Encode(endLog{common.Bytes2Hex(output), math.HexOrDecimal64(gasUsed), errMsg})
+}
+
+func (l *jsonLogger) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	l.env = env
+	l.storage = make(map[common.Address]Storage)
+}
@@ -0,0 +1,192 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracers/internal/tracetest"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// storeCode stores 0x2a into slot 0x01, loads it back and returns it.
+	storeCode = []byte{
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x01, byte(vm.SLOAD),
+		byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}
+)
+
+// execute runs code at tracetest.Contract under the given tracer and returns
+// the output of the call.
+func execute(t *testing.T, code []byte, hooks *tracing.Hooks) ([]byte, error) {
+	t.Helper()
+
+	statedb := state.NewAccountStateDb()
+	statedb.SetCode(tracetest.Contract, code)
+
+	evm := tracetest.NewEVM(statedb, hooks)
+	hooks.OnTxStart(evm.GetVMContext(), nil, tracetest.Caller)
+	ret, _, err := evm.Call(vm.AccountRef(tracetest.Caller), tracetest.Contract, nil, 100000, new(uint256.Int))
+	return ret, err
+}
+
+func TestStoreCapture(t *testing.T) {
+	logger := NewStructLogger(nil)
+	if _, err := execute(t, storeCode, logger.Hooks()); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	logs := logger.StructLogs()
+	if len(logs) != 10 {
+		t.Fatalf("expected 10 steps, got %d", len(logs))
+	}
+	sstore := logs[2]
+	if sstore.Op != vm.SSTORE {
+		t.Fatalf("expected SSTORE at step 2, got %v", sstore.Op)
+	}
+	slot := common.HexToHash("0x01")
+	if have, want := sstore.Storage[slot], common.HexToHash("0x2a"); have != want {
+		t.Errorf("expected storage %x, got %x", want, have)
+	}
+	if len(sstore.Stack) != 2 || sstore.Depth != 1 {
+		t.Errorf("unexpected SSTORE step: stack %d, depth %d", len(sstore.Stack), sstore.Depth)
+	}
+	if logs[0].Storage != nil {
+		t.Errorf("expected no storage for PUSH1, got %v", logs[0].Storage)
+	}
+	if have := logs[4].Storage[slot]; logs[4].Op != vm.SLOAD || have != common.HexToHash("0x2a") {
+		t.Errorf("unexpected SLOAD step: %v %x", logs[4].Op, have)
+	}
+	if logs[0].Memory != nil {
+		t.Error("memory captured while disabled")
+	}
+	if have, want := logger.Output(), common.LeftPadBytes([]byte{0x2a}, 32); !bytes.Equal(have, want) {
+		t.Errorf("output mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestStructLoggerConfig(t *testing.T) {
+	logger := NewStructLogger(&Config{EnableMemory: true, DisableStack: true, DisableStorage: true, Limit: 9})
+	if _, err := execute(t, storeCode, logger.Hooks()); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	logs := logger.StructLogs()
+	if len(logs) != 9 {
+		t.Fatalf("expected 9 steps, got %d", len(logs))
+	}
+	for i, log := range logs {
+		if log.Stack != nil {
+			t.Errorf("step %d: stack captured while disabled", i)
+		}
+		if log.Storage != nil {
+			t.Errorf("step %d: storage captured while disabled", i)
+		}
+	}
+	// Memory holds the stored word after MSTORE.
+	if have, want := logs[8].Memory, common.LeftPadBytes([]byte{0x2a}, 32); !bytes.Equal(have, want) {
+		t.Errorf("memory mismatch: have %x, want %x", have, want)
+	}
+	res, err := logger.GetResult()
+	if err != nil {
+		t.Fatalf("failed to get result: %v", err)
+	}
+	var result ExecutionResult
+	if err := json.Unmarshal(res, &result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if result.Failed || len(result.StructLogs) != 9 || result.StructLogs[2].Op != "SSTORE" {
+		t.Errorf("unexpected result: %s", res)
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := execute(t, storeCode, NewJSONLogger(nil, &out)); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid json line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 11 {
+		t.Fatalf("expected 10 steps and a summary, got %d lines", len(lines))
+	}
+	first := lines[0]
+	for _, field := range []string{"pc", "op", "gas", "gasCost", "memSize", "stack", "depth", "refund", "opName"} {
+		if _, ok := first[field]; !ok {
+			t.Errorf("step is missing field %q: %v", field, first)
+		}
+	}
+	if first["opName"] != "PUSH1" || first["gas"] != "0x186a0" || first["depth"] != 1.0 {
+		t.Errorf("unexpected first step: %v", first)
+	}
+	if _, ok := first["storage"]; ok {
+		t.Errorf("storage reported for PUSH1: %v", first)
+	}
+	storage, ok := lines[2]["storage"].(map[string]interface{})
+	if !ok || storage[common.HexToHash("0x01").Hex()] != common.HexToHash("0x2a").Hex() {
+		t.Errorf("unexpected SSTORE storage: %v", lines[2])
+	}
+	summary := lines[10]
+	if summary["output"] != common.Bytes2Hex(common.LeftPadBytes([]byte{0x2a}, 32)) || summary["gasUsed"] == nil {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
+
+func TestJSONLoggerFault(t *testing.T) {
+	var out bytes.Buffer
+	_, err := execute(t, []byte{byte(vm.PUSH1), 0x00, 0xfe}, NewJSONLogger(&Config{DisableStorage: true}, &out))
+	var invalid *vm.ErrInvalidOpCode
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected invalid opcode error, got %v", err)
+	}
+	var (
+		scanner = bufio.NewScanner(&out)
+		last    map[string]interface{}
+		faulted bool
+	)
+	for scanner.Scan() {
+		last = nil
+		if err := json.Unmarshal(scanner.Bytes(), &last); err != nil {
+			t.Fatalf("invalid json line %q: %v", scanner.Text(), err)
+		}
+		if last["opName"] == "INVALID" && last["error"] != nil {
+			faulted = true
+		}
+	}
+	if !faulted {
+		t.Error("fault step not reported")
+	}
+	if last["error"] == nil {
+		t.Errorf("summary lacks the error: %v", last)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracers"
+	"github.com/a1146910248/mixchain/mvm/tracers/internal/tracetest"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+var (
+	logTopic = common.HexToHash("0xa1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1")
+	// logCode emits a single LOG1 with logTopic and no data.
+	logCode = append(append([]byte{byte(vm.PUSH32)}, logTopic.Bytes()...),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.LOG1))
+)
+
+// revertCode runs prefix and then reverts with the abi encoded Error(string)
+// of the given reason.
+func revertCode(prefix []byte, reason string) []byte {
+	payload := common.FromHex("0x08c379a0")
+	payload = append(payload, common.LeftPadBytes([]byte{0x20}, 32)...)
+	payload = append(payload, common.LeftPadBytes(big.NewInt(int64(len(reason))).Bytes(), 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), (len(reason)+31)/32*32)...)
+
+	code := append(common.CopyBytes(prefix),
+		byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), byte(len(prefix)+12), byte(vm.PUSH1), 0x00, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), 0x00, byte(vm.REVERT),
+	)
+	return append(code, payload...)
+}
+
+// callCode runs prefix, calls addr without input and stops.
+func callCode(prefix []byte, addr common.Address) []byte {
+	code := append(common.CopyBytes(prefix),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20))
+	code = append(code, addr.Bytes()...)
+	return append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP), byte(vm.STOP))
+}
+
+// traceCall runs a call of tracetest.Contract with the named tracer and
+// returns the decoded top call frame.
+func traceCall(t *testing.T, config string, code map[common.Address][]byte) *callFrame {
+	t.Helper()
+
+	tracer, err := tracers.DefaultDirectory.New("callTracer", new(tracers.Context), json.RawMessage(config))
+	if err != nil {
+		t.Fatalf("failed to create call tracer: %v", err)
+	}
+	statedb := state.NewAccountStateDb()
+	for addr, c := range code {
+		statedb.SetCode(addr, c)
+	}
+	evm := tracetest.NewEVM(statedb, tracer.Hooks)
+
+	tx := types.NewTx(&types.LegacyTx{To: &tracetest.Contract, Gas: 100000, GasPrice: new(big.Int)})
+	tracer.OnTxStart(evm.GetVMContext(), tx, tracetest.Caller)
+	_, _, callErr := evm.Call(vm.AccountRef(tracetest.Caller), tracetest.Contract, []byte{0x01, 0x02}, tx.Gas(), new(uint256.Int))
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	frame := new(callFrame)
+	if err := json.Unmarshal(res, frame); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	// Failing calls are traced too, their error must show up on the frame.
+	if callErr != nil && frame.Error != callErr.Error() || callErr == nil && frame.Error != "" {
+		t.Errorf("call error mismatch: have %q, want %v", frame.Error, callErr)
+	}
+	// The type is not decoded, check it on the raw result.
+	var raw map[string]interface{}
+	if err := json.Unmarshal(res, &raw); err != nil {
+		t.Fatalf("failed to unmarshal raw trace result: %v", err)
+	}
+	if raw["type"] != "CALL" {
+		t.Errorf("unexpected top call type: %v", raw["type"])
+	}
+	return frame
+}
+
+func TestCallTracerNested(t *testing.T) {
+	frame := traceCall(t, "", map[common.Address][]byte{
+		tracetest.Contract: callCode(nil, tracetest.Callee),
+		tracetest.Callee:   revertCode(nil, "oops"),
+	})
+	if frame.From != tracetest.Caller || *frame.To != tracetest.Contract || frame.Gas != 100000 || frame.Error != "" {
+		t.Errorf("unexpected top frame: %+v", frame)
+	}
+	if have, want := common.Bytes2Hex(frame.Input), "0102"; have != want {
+		t.Errorf("input mismatch: have %s, want %s", have, want)
+	}
+	if len(frame.Calls) != 1 {
+		t.Fatalf("expected one nested call, got %d", len(frame.Calls))
+	}
+	inner := frame.Calls[0]
+	if inner.From != tracetest.Contract || *inner.To != tracetest.Callee {
+		t.Errorf("unexpected inner frame: %+v", inner)
+	}
+	if inner.Error != vm.ErrExecutionReverted.Error() || inner.RevertReason != "oops" {
+		t.Errorf("unexpected inner error: %q, reason %q", inner.Error, inner.RevertReason)
+	}
+	if inner.GasUsed == 0 || frame.GasUsed <= inner.GasUsed {
+		t.Errorf("unexpected gas used: top %d, inner %d", frame.GasUsed, inner.GasUsed)
+	}
+	if len(inner.Output) != 100 {
+		t.Errorf("expected revert data as output, got %x", inner.Output)
+	}
+}
+
+func TestCallTracerRevert(t *testing.T) {
+	frame := traceCall(t, "", map[common.Address][]byte{
+		tracetest.Contract: revertCode(nil, "top level failure"),
+	})
+	if frame.Error != vm.ErrExecutionReverted.Error() || frame.RevertReason != "top level failure" {
+		t.Errorf("unexpected top frame error: %q, reason %q", frame.Error, frame.RevertReason)
+	}
+}
+
+func TestCallTracerOnlyTopCall(t *testing.T) {
+	frame := traceCall(t, `{"onlyTopCall": true}`, map[common.Address][]byte{
+		tracetest.Contract: callCode(nil, tracetest.Callee),
+		tracetest.Callee:   revertCode(nil, "oops"),
+	})
+	if len(frame.Calls) != 0 {
+		t.Errorf("expected no nested calls, got %d", len(frame.Calls))
+	}
+}
+
+func TestCallTracerWithLog(t *testing.T) {
+	code := map[common.Address][]byte{
+		tracetest.Contract: callCode(logCode, tracetest.Callee),
+		tracetest.Callee:   revertCode(logCode, "oops"),
+	}
+	frame := traceCall(t, `{"withLog": true}`, code)
+	if len(frame.Logs) != 1 {
+		t.Fatalf("expected one log in top frame, got %d", len(frame.Logs))
+	}
+	if l := frame.Logs[0]; l.Address != tracetest.Contract || len(l.Topics) != 1 || l.Topics[0] != logTopic || l.Position != 0 {
+		t.Errorf("unexpected log: %+v", l)
+	}
+	// The logs of the reverted inner call are dropped.
+	if len(frame.Calls) != 1 || len(frame.Calls[0].Logs) != 0 {
+		t.Errorf("expected the reverted call without logs, got %+v", frame.Calls)
+	}
+	if frame = traceCall(t, "", code); len(frame.Logs) != 0 {
+		t.Errorf("logs collected while disabled: %+v", frame.Logs)
+	}
+}
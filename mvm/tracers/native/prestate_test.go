@@ -0,0 +1,178 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracers"
+	"github.com/a1146910248/mixchain/mvm/tracers/internal/tracetest"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+var (
+	slotOne   = common.HexToHash("0x01")
+	slotTwo   = common.HexToHash("0x02")
+	storeCode = []byte{
+		byte(vm.PUSH1), 0x02, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+)
+
+// tracePrestate sends 5 wei to tracetest.Contract running storeCode with slot
+// one set to 0x07 and slot two set to 0x08 beforehand, and returns the raw
+// result of the prestate tracer.
+func tracePrestate(t *testing.T, config string) json.RawMessage {
+	t.Helper()
+
+	tracer, err := tracers.DefaultDirectory.New("prestateTracer", new(tracers.Context), json.RawMessage(config))
+	if err != nil {
+		t.Fatalf("failed to create prestate tracer: %v", err)
+	}
+	statedb := state.NewAccountStateDb()
+	statedb.AddBalance(tracetest.Caller, uint256.NewInt(100), tracing.BalanceChangeUnspecified)
+	statedb.SetNonce(tracetest.Caller, 3)
+	statedb.SetCode(tracetest.Contract, storeCode)
+	statedb.SetState(tracetest.Contract, slotOne, common.HexToHash("0x07"))
+	statedb.SetState(tracetest.Contract, slotTwo, common.HexToHash("0x08"))
+
+	evm := tracetest.NewEVM(statedb, tracer.Hooks)
+	tx := types.NewTx(&types.LegacyTx{To: &tracetest.Contract, Gas: 100000, GasPrice: new(big.Int), Value: big.NewInt(5)})
+	tracer.OnTxStart(evm.GetVMContext(), tx, tracetest.Caller)
+	if _, _, err := evm.Call(vm.AccountRef(tracetest.Caller), tracetest.Contract, nil, tx.Gas(), uint256.NewInt(5)); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	tracer.OnTxEnd(&types.Receipt{}, nil)
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+func TestPrestateTracer(t *testing.T) {
+	var pre stateMap
+	if err := json.Unmarshal(tracePrestate(t, ""), &pre); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	// The coinbase is untouched and empty.
+	if len(pre) != 2 {
+		t.Fatalf("expected caller and contract in prestate, got %d accounts", len(pre))
+	}
+	if caller := pre[tracetest.Caller]; caller == nil || caller.Balance.Uint64() != 100 || caller.Nonce != 3 || len(caller.Code) != 0 {
+		t.Errorf("unexpected caller prestate: %+v", caller)
+	}
+	contract := pre[tracetest.Contract]
+	if contract == nil || !bytes.Equal(contract.Code, storeCode) || contract.Balance.Sign() != 0 {
+		t.Fatalf("unexpected contract prestate: %+v", contract)
+	}
+	want := map[common.Hash]common.Hash{slotOne: common.HexToHash("0x07"), slotTwo: common.HexToHash("0x08")}
+	if len(contract.Storage) != len(want) || contract.Storage[slotOne] != want[slotOne] || contract.Storage[slotTwo] != want[slotTwo] {
+		t.Errorf("unexpected contract storage: %v", contract.Storage)
+	}
+
+	pre = nil
+	if err := json.Unmarshal(tracePrestate(t, `{"disableCode": true, "disableStorage": true}`), &pre); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if contract := pre[tracetest.Contract]; contract == nil || contract.Code != nil || contract.Storage != nil {
+		t.Errorf("code or storage reported while disabled: %+v", contract)
+	}
+}
+
+func TestPrestateTracerDiffMode(t *testing.T) {
+	var diff struct {
+		Pre  stateMap `json:"pre"`
+		Post stateMap `json:"post"`
+	}
+	if err := json.Unmarshal(tracePrestate(t, `{"diffMode": true}`), &diff); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if len(diff.Pre) != 2 || len(diff.Post) != 2 {
+		t.Fatalf("expected two modified accounts, got pre %d, post %d", len(diff.Pre), len(diff.Post))
+	}
+	if caller := diff.Post[tracetest.Caller]; caller == nil || caller.Balance.Uint64() != 95 || caller.Nonce != 0 {
+		t.Errorf("unexpected caller poststate: %+v", caller)
+	}
+	pre, post := diff.Pre[tracetest.Contract], diff.Post[tracetest.Contract]
+	if pre == nil || post == nil {
+		t.Fatal("contract missing from the diff")
+	}
+	// Only the written slot is reported, the code is unchanged.
+	if len(pre.Storage) != 1 || pre.Storage[slotOne] != common.HexToHash("0x07") {
+		t.Errorf("unexpected contract prestate storage: %v", pre.Storage)
+	}
+	if len(post.Storage) != 1 || post.Storage[slotOne] != common.HexToHash("0x2a") || post.Code != nil {
+		t.Errorf("unexpected contract poststate: %+v", post)
+	}
+	if post.Balance.Uint64() != 5 {
+		t.Errorf("unexpected contract balance: %v", post.Balance)
+	}
+
+	if _, err := tracers.DefaultDirectory.New("prestateTracer", new(tracers.Context), json.RawMessage(`{"diffMode": true, "includeEmpty": true}`)); err == nil {
+		t.Error("expected diffMode with includeEmpty to be rejected")
+	}
+}
+
+// TestPrestateTracerStateHooks checks that accounts only changed through the
+// state change hooks, without any opcode reading them, are reported.
+func TestPrestateTracerStateHooks(t *testing.T) {
+	tracer, err := tracers.DefaultDirectory.New("prestateTracer", new(tracers.Context), json.RawMessage(`{"diffMode": true}`))
+	if err != nil {
+		t.Fatalf("failed to create prestate tracer: %v", err)
+	}
+	statedb := state.NewAccountStateDb()
+	statedb.AddBalance(tracetest.Callee, uint256.NewInt(4), tracing.BalanceChangeUnspecified)
+	statedb.SetNonce(tracetest.Callee, 1)
+	statedb.SetState(tracetest.Callee, slotOne, common.HexToHash("0x03"))
+	evm := tracetest.NewEVM(statedb, tracer.Hooks)
+	tracer.OnTxStart(evm.GetVMContext(), nil, tracetest.Caller)
+
+	hooked := state.NewHookedState(statedb, tracer.Hooks)
+	hooked.AddBalance(tracetest.Callee, uint256.NewInt(10), tracing.BalanceChangeTransfer)
+	hooked.SetNonce(tracetest.Callee, 2)
+	hooked.SetState(tracetest.Callee, slotOne, common.HexToHash("0x09"))
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var diff struct {
+		Pre  stateMap `json:"pre"`
+		Post stateMap `json:"post"`
+	}
+	if err := json.Unmarshal(res, &diff); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	pre, post := diff.Pre[tracetest.Callee], diff.Post[tracetest.Callee]
+	if pre == nil || pre.Balance.Uint64() != 4 || pre.Nonce != 1 || pre.Storage[slotOne] != common.HexToHash("0x03") {
+		t.Errorf("unexpected prestate: %+v", pre)
+	}
+	if post == nil || post.Balance.Uint64() != 14 || post.Nonce != 2 || post.Storage[slotOne] != common.HexToHash("0x09") {
+		t.Errorf("unexpected poststate: %+v", post)
+	}
+}
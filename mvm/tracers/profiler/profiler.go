@@ -0,0 +1,397 @@
+// Package profiler implements a gas profiler on tracing.Hooks. It adds up the
+// gas and wall time spent per contract, per opcode, per program counter and,
+// given solc source maps, per Solidity source line, and exports the results as
+// folded stacks for flame graph tools and as pprof profiles.
+package profiler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/google/pprof/profile"
+)
+
+// Stat is the cost aggregated for a contract, an opcode, a program counter or
+// a source line.
+type Stat struct {
+	Gas   uint64        // Gas spent, excluding the gas used by nested calls
+	Time  time.Duration // Wall time spent, excluding the time of nested calls
+	Count uint64        // Number of executed instructions
+}
+
+func (s *Stat) add(gas uint64, d time.Duration, count uint64) {
+	s.Gas += gas
+	s.Time += d
+	s.Count += count
+}
+
+// PC identifies an instruction of the code deployed at an address.
+type PC struct {
+	Address common.Address
+	PC      uint64
+}
+
+// Line identifies a line of a Solidity source file.
+type Line struct {
+	File string
+	Line int
+}
+
+// contract is the information registered for a contract address.
+type contract struct {
+	name   string
+	srcMap *SourceMap
+}
+
+// step is an executed instruction whose cost is settled once the next
+// instruction of the same frame runs, or the frame exits.
+type step struct {
+	op       vm.OpCode
+	pc       uint64
+	gas      uint64        // Gas available before the instruction
+	start    time.Time     // Time the instruction started
+	childGas uint64        // Gas used by the calls made by the instruction
+	childDur time.Duration // Time spent in the calls made by the instruction
+}
+
+// site is a position on the call stack, a location in the pprof profile.
+type site struct {
+	PC
+	op   vm.OpCode
+	code bool // Whether an instruction executes at the position
+	init bool // Whether the instruction belongs to init code
+}
+
+// frame is a call frame on the profiled call stack.
+type frame struct {
+	addr     common.Address // Address of the executing code
+	create   bool           // Whether init code is executing
+	folded   string         // Folded call path up to and including this frame
+	sites    []site         // Call sites of the parent frames, outermost first
+	start    time.Time
+	pending  *step
+	own      uint64 // Gas settled for the instructions of this frame
+	children uint64 // Gas used by the calls made from this frame
+}
+
+// sample is the cost aggregated for a call path.
+type sample struct {
+	sites []site // Call sites, outermost first
+	leaf  site
+	stat  Stat
+}
+
+// Profiler aggregates the cost of the executions it traces. It is not safe
+// for concurrent use, results accumulate over all traced executions.
+type Profiler struct {
+	contracts map[common.Address]*contract
+	frames    []*frame
+
+	byContract map[common.Address]*Stat
+	byOp       map[vm.OpCode]*Stat
+	byPC       map[PC]*Stat
+	byLine     map[Line]*Stat
+	folded     map[string]uint64
+	samples    map[string]*sample
+}
+
+// New creates an empty profiler.
+func New() *Profiler {
+	return &Profiler{
+		contracts:  make(map[common.Address]*contract),
+		byContract: make(map[common.Address]*Stat),
+		byOp:       make(map[vm.OpCode]*Stat),
+		byPC:       make(map[PC]*Stat),
+		byLine:     make(map[Line]*Stat),
+		folded:     make(map[string]uint64),
+		samples:    make(map[string]*sample),
+	}
+}
+
+// AddContract names the contract deployed at addr in the exported stacks and,
+// if srcMap is not nil, attributes the cost of its runtime code to source
+// lines. The name must not contain spaces or semicolons.
+func (p *Profiler) AddContract(addr common.Address, name string, srcMap *SourceMap) {
+	p.contracts[addr] = &contract{name: name, srcMap: srcMap}
+}
+
+// Hooks returns the tracing hooks feeding the profiler.
+func (p *Profiler) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnEnter:  p.OnEnter,
+		OnExit:   p.OnExit,
+		OnOpcode: p.OnOpcode,
+	}
+}
+
+// OnEnter pushes a frame for the code starting to execute.
+func (p *Profiler) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if depth == 0 {
+		p.frames = p.frames[:0]
+	}
+	op := vm.OpCode(typ)
+	f := &frame{
+		addr:   to,
+		create: op == vm.CREATE || op == vm.CREATE2,
+		folded: p.label(to),
+		start:  time.Now(),
+	}
+	if parent := p.current(); parent != nil {
+		f.folded = parent.folded + ";" + f.folded
+		call := site{PC: PC{Address: parent.addr}, init: parent.create}
+		if s := parent.pending; s != nil {
+			call.PC.PC, call.op, call.code = s.pc, s.op, true
+		}
+		f.sites = append(append([]site{}, parent.sites...), call)
+	}
+	p.frames = append(p.frames, f)
+}
+
+// OnExit settles the last instruction of the frame and charges the gas and
+// time of the frame to the instruction of the parent which made the call.
+func (p *Profiler) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	f := p.current()
+	if f == nil {
+		return
+	}
+	p.frames = p.frames[:len(p.frames)-1]
+
+	// The last instruction is charged whatever was not settled, which covers
+	// the gas burnt by a failing frame.
+	var rest uint64
+	if spent := f.own + f.children; gasUsed > spent {
+		rest = gasUsed - spent
+	}
+	now := time.Now()
+	if s := f.pending; s != nil {
+		p.record(f, s, rest, now.Sub(s.start)-s.childDur)
+	} else {
+		// Precompiles and accounts without code execute no instructions.
+		p.recordFrame(f, rest, now.Sub(f.start))
+	}
+	if parent := p.current(); parent != nil {
+		parent.children += gasUsed
+		if s := parent.pending; s != nil {
+			s.childGas += gasUsed
+			s.childDur += now.Sub(f.start)
+		}
+	}
+}
+
+// OnOpcode settles the previous instruction of the frame and records the
+// one about to execute.
+func (p *Profiler) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	f := p.current()
+	if f == nil {
+		return
+	}
+	now := time.Now()
+	if s := f.pending; s != nil {
+		var spent uint64
+		if used := s.gas - gas; s.gas > gas && used > s.childGas {
+			spent = used - s.childGas
+		}
+		f.own += spent
+		p.record(f, s, spent, now.Sub(s.start)-s.childDur)
+	}
+	f.pending = &step{op: vm.OpCode(op), pc: pc, gas: gas, start: now}
+}
+
+func (p *Profiler) current() *frame {
+	if len(p.frames) == 0 {
+		return nil
+	}
+	return p.frames[len(p.frames)-1]
+}
+
+// label returns the name of the contract at addr, or its address.
+func (p *Profiler) label(addr common.Address) string {
+	if c := p.contracts[addr]; c != nil && c.name != "" {
+		return c.name
+	}
+	return addr.Hex()
+}
+
+// location returns the source location of an instruction of the runtime code
+// of a frame.
+func (p *Profiler) location(f *frame, pc uint64) (SourceLocation, bool) {
+	c := p.contracts[f.addr]
+	if f.create || c == nil || c.srcMap == nil {
+		return SourceLocation{}, false
+	}
+	return c.srcMap.Lookup(pc)
+}
+
+// record charges gas and time to a settled instruction.
+func (p *Profiler) record(f *frame, s *step, gas uint64, d time.Duration) {
+	key := PC{f.addr, s.pc}
+	stat(p.byContract, f.addr).add(gas, d, 1)
+	stat(p.byOp, s.op).add(gas, d, 1)
+	stat(p.byPC, key).add(gas, d, 1)
+
+	leaf := fmt.Sprintf("%v@%d", s.op, s.pc)
+	if loc, ok := p.location(f, s.pc); ok {
+		stat(p.byLine, Line{loc.File, loc.Line}).add(gas, d, 1)
+		leaf = loc.String() + ";" + leaf
+	}
+	p.folded[f.folded+";"+leaf] += gas
+
+	p.sample(f, site{PC: key, op: s.op, code: true, init: f.create}).stat.add(gas, d, 1)
+}
+
+// recordFrame charges gas and time to a frame which executed no instructions.
+func (p *Profiler) recordFrame(f *frame, gas uint64, d time.Duration) {
+	stat(p.byContract, f.addr).add(gas, d, 0)
+	p.folded[f.folded] += gas
+	p.sample(f, site{PC: PC{Address: f.addr}, init: f.create}).stat.add(gas, d, 0)
+}
+
+// sample returns the sample of the call path of f ending in leaf.
+func (p *Profiler) sample(f *frame, leaf site) *sample {
+	var key strings.Builder
+	for _, s := range append(f.sites, leaf) {
+		fmt.Fprintf(&key, "%x:%d:%d:%t:%t;", s.Address, s.PC.PC, s.op, s.code, s.init)
+	}
+	s := p.samples[key.String()]
+	if s == nil {
+		s = &sample{sites: f.sites, leaf: leaf}
+		p.samples[key.String()] = s
+	}
+	return s
+}
+
+// stat returns the entry of m for key, creating it if needed.
+func stat[K comparable](m map[K]*Stat, key K) *Stat {
+	s := m[key]
+	if s == nil {
+		s = new(Stat)
+		m[key] = s
+	}
+	return s
+}
+
+// Contracts returns the cost aggregated per address of executed code.
+func (p *Profiler) Contracts() map[common.Address]Stat { return copyStats(p.byContract) }
+
+// Opcodes returns the cost aggregated per opcode.
+func (p *Profiler) Opcodes() map[vm.OpCode]Stat { return copyStats(p.byOp) }
+
+// PCs returns the cost aggregated per instruction.
+func (p *Profiler) PCs() map[PC]Stat { return copyStats(p.byPC) }
+
+// Lines returns the cost aggregated per Solidity source line, for the
+// contracts registered with a source map.
+func (p *Profiler) Lines() map[Line]Stat { return copyStats(p.byLine) }
+
+func copyStats[K comparable](m map[K]*Stat) map[K]Stat {
+	cpy := make(map[K]Stat, len(m))
+	for k, s := range m {
+		cpy[k] = *s
+	}
+	return cpy
+}
+
+// WriteFolded writes the gas spent per call path in the folded stack format
+// read by flamegraph.pl and compatible tools: one "frame;frame;... gas" line
+// per path. The frames are the contracts on the call stack, followed by the
+// source line if known and the instruction as OPCODE@pc.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.folded))
+	for stack := range p.folded {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	bw := bufio.NewWriter(w)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(bw, "%s %d\n", stack, p.folded[stack]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Profile converts the aggregated cost into a pprof profile with gas, time
+// and instruction count samples. Every instruction is a location whose
+// address is the program counter, attributed to an opcode function inlined
+// into a function per contract, which carries the source line if known.
+func (p *Profiler) Profile() *profile.Profile {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "gas", Unit: "gas"},
+			{Type: "time", Unit: "nanoseconds"},
+			{Type: "instructions", Unit: "count"},
+		},
+		DefaultSampleType: "gas",
+		PeriodType:        &profile.ValueType{Type: "gas", Unit: "gas"},
+		Period:            1,
+	}
+	var (
+		funcs = make(map[string]*profile.Function)
+		locs  = make(map[site]*profile.Location)
+	)
+	function := func(name, file string) *profile.Function {
+		key := name + "\x00" + file
+		if fn := funcs[key]; fn != nil {
+			return fn
+		}
+		fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name, SystemName: name, Filename: file}
+		prof.Function = append(prof.Function, fn)
+		funcs[key] = fn
+		return fn
+	}
+	location := func(s site) *profile.Location {
+		if loc := locs[s]; loc != nil {
+			return loc
+		}
+		loc := &profile.Location{ID: uint64(len(prof.Location) + 1), Address: s.PC.PC}
+		if s.code {
+			loc.Line = append(loc.Line, profile.Line{Function: function(s.op.String(), "")})
+		}
+		var (
+			file string
+			line int64
+		)
+		if c := p.contracts[s.Address]; c != nil && c.srcMap != nil && s.code && !s.init {
+			if src, ok := c.srcMap.Lookup(s.PC.PC); ok {
+				file, line = src.File, int64(src.Line)
+			}
+		}
+		loc.Line = append(loc.Line, profile.Line{Function: function(p.label(s.Address), file), Line: line})
+		prof.Location = append(prof.Location, loc)
+		locs[s] = loc
+		return loc
+	}
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := p.samples[key]
+		// Locations are listed from the leaf to the outermost call site.
+		sample := &profile.Sample{
+			Location: []*profile.Location{location(s.leaf)},
+			Value:    []int64{int64(s.stat.Gas), int64(s.stat.Time), int64(s.stat.Count)},
+		}
+		for i := len(s.sites) - 1; i >= 0; i-- {
+			sample.Location = append(sample.Location, location(s.sites[i]))
+		}
+		prof.Sample = append(prof.Sample, sample)
+	}
+	return prof
+}
+
+// WriteProfile writes the pprof profile as gzip compressed protobuf, as read
+// by go tool pprof.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	return p.Profile().Write(w)
+}
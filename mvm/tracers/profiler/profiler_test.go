@@ -0,0 +1,189 @@
+package profiler
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/compiler"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracers/internal/tracetest"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/google/pprof/profile"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// storeCode stores 0x01 into slot 0x00.
+	storeCode   = []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP)}
+	storeSource = "contract Store {\n    uint x;\n    function f() { x = 1; }\n}\n"
+	// storeSrcMap maps the first push to the contract on line 1, the second
+	// push and the SSTORE to "x = 1" on line 3 and the STOP to no source.
+	storeSrcMap = "0:60:0:-;48:5;;0:0:-1"
+
+	// callerCode calls tracetest.Callee without input and stops.
+	callerCode = append(append([]byte{
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20)}, tracetest.Callee.Bytes()...),
+		byte(vm.GAS), byte(vm.CALL), byte(vm.POP), byte(vm.STOP))
+)
+
+// run runs a call of tracetest.Contract under p and returns the gas used.
+func run(t *testing.T, p *Profiler) uint64 {
+	t.Helper()
+
+	statedb := state.NewAccountStateDb()
+	statedb.SetCode(tracetest.Contract, callerCode)
+	statedb.SetCode(tracetest.Callee, storeCode)
+	evm := tracetest.NewEVM(statedb, p.Hooks())
+
+	const gas = 100000
+	_, left, err := evm.Call(vm.AccountRef(tracetest.Caller), tracetest.Contract, nil, gas, new(uint256.Int))
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return gas - left
+}
+
+func TestProfilerStats(t *testing.T) {
+	p := New()
+	used := run(t, p)
+
+	var total uint64
+	for _, s := range p.Contracts() {
+		total += s.Gas
+	}
+	if total != used {
+		t.Errorf("contract gas mismatch: have %d, want %d", total, used)
+	}
+	callee := p.Contracts()[tracetest.Callee]
+	if callee.Count != 4 {
+		t.Errorf("unexpected callee instructions: %d", callee.Count)
+	}
+	// Cold zero to non-zero store.
+	if have, want := p.Opcodes()[vm.SSTORE].Gas, params.SstoreSetGasEIP2200+params.ColdSloadCostEIP2929; have != want {
+		t.Errorf("SSTORE gas mismatch: have %d, want %d", have, want)
+	}
+	// The CALL is charged its own cost only, not the gas used by the callee.
+	call := p.PCs()[PC{tracetest.Contract, 32}]
+	if call.Count != 1 || call.Gas == 0 || call.Gas >= callee.Gas {
+		t.Errorf("unexpected CALL cost: %+v, callee %+v", call, callee)
+	}
+	if len(p.Lines()) != 0 {
+		t.Errorf("lines reported without source maps: %v", p.Lines())
+	}
+}
+
+func TestProfilerFolded(t *testing.T) {
+	p := New()
+	p.AddContract(tracetest.Contract, "Caller", nil)
+	used := run(t, p)
+
+	var out bytes.Buffer
+	if err := p.WriteFolded(&out); err != nil {
+		t.Fatalf("failed to write folded stacks: %v", err)
+	}
+	var (
+		total uint64
+		found bool
+	)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		stack, gas, ok := strings.Cut(line, " ")
+		if !ok {
+			t.Fatalf("malformed line %q", line)
+		}
+		n, err := strconv.ParseUint(gas, 10, 64)
+		if err != nil {
+			t.Fatalf("malformed gas in line %q", line)
+		}
+		total += n
+		if stack == "Caller;"+tracetest.Callee.Hex()+";SSTORE@4" {
+			found = true
+		}
+	}
+	if total != used {
+		t.Errorf("folded gas mismatch: have %d, want %d", total, used)
+	}
+	if !found {
+		t.Errorf("SSTORE stack missing:\n%s", out.String())
+	}
+}
+
+func TestProfilerPprof(t *testing.T) {
+	p := New()
+	used := run(t, p)
+
+	var out bytes.Buffer
+	if err := p.WriteProfile(&out); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	prof, err := profile.Parse(&out)
+	if err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	var total int64
+	for _, s := range prof.Sample {
+		total += s.Value[0]
+		// The leaf of the store is the SSTORE inlined into the callee,
+		// called from the CALL of the caller.
+		if leaf := s.Location[0]; leaf.Line[0].Function.Name == "SSTORE" {
+			if len(s.Location) != 2 || leaf.Line[1].Function.Name != tracetest.Callee.Hex() || s.Location[1].Line[0].Function.Name != "CALL" {
+				t.Errorf("unexpected SSTORE stack: %v", s)
+			}
+		}
+	}
+	if total != int64(used) {
+		t.Errorf("profile gas mismatch: have %d, want %d", total, used)
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	m, err := NewSourceMap(append(storeCode, 0xa2, 0x64), storeSrcMap, []Source{{Name: "Store.sol", Code: storeSource}})
+	if err != nil {
+		t.Fatalf("failed to parse source map: %v", err)
+	}
+	for pc, want := range map[uint64]string{0: "Store.sol:1", 2: "Store.sol:3", 4: "Store.sol:3"} {
+		if loc, ok := m.Lookup(pc); !ok || loc.String() != want {
+			t.Errorf("pc %d: have %v, want %s", pc, loc, want)
+		}
+	}
+	if loc, ok := m.Lookup(5); ok {
+		t.Errorf("generated STOP mapped to %v", loc)
+	}
+	if _, err := NewSourceMap(storeCode, "0:x:0", nil); err == nil {
+		t.Error("expected invalid source map to be rejected")
+	}
+}
+
+func TestProfilerLines(t *testing.T) {
+	m, err := NewContractSourceMap("Store.sol", &compiler.Contract{
+		RuntimeCode: common.Bytes2Hex(storeCode),
+		Info:        compiler.ContractInfo{Source: storeSource, SrcMapRuntime: storeSrcMap},
+	})
+	if err != nil {
+		t.Fatalf("failed to build source map: %v", err)
+	}
+	p := New()
+	p.AddContract(tracetest.Callee, "Store", m)
+	run(t, p)
+
+	lines := p.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("expected two source lines, got %v", lines)
+	}
+	// The STOP is free and not mapped.
+	if have, want := lines[Line{"Store.sol", 1}].Gas+lines[Line{"Store.sol", 3}].Gas, p.Contracts()[tracetest.Callee].Gas; have != want {
+		t.Errorf("line gas mismatch: have %d, want %d", have, want)
+	}
+
+	var out bytes.Buffer
+	if err := p.WriteFolded(&out); err != nil {
+		t.Fatalf("failed to write folded stacks: %v", err)
+	}
+	if !strings.Contains(out.String(), ";Store;Store.sol:3;SSTORE@4 ") {
+		t.Errorf("source line missing from folded stacks:\n%s", out.String())
+	}
+}
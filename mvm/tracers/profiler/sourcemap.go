@@ -0,0 +1,133 @@
+package profiler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/compiler"
+	"github.com/a1146910248/mixchain/mvm/vm"
+)
+
+// SourceLocation is the Solidity source range an instruction was generated
+// from.
+type SourceLocation struct {
+	File   string // Name of the source file
+	Offset int    // Byte offset of the range in the source
+	Length int    // Byte length of the range
+	Line   int    // 1-based line of the range start
+}
+
+// String returns the location as file:line.
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Source is a Solidity source file referenced by the file indexes of a
+// source map, in the order of the solc source list.
+type Source struct {
+	Name string
+	Code string
+}
+
+// SourceMap maps the program counters of a runtime bytecode to the Solidity
+// source lines they were compiled from.
+type SourceMap struct {
+	locs map[uint64]SourceLocation
+}
+
+// NewSourceMap decodes a solc source map, which holds one s:l:f:j:m entry
+// per instruction of code, and resolves it against the given sources.
+// Instructions generated by the compiler without a source, or referencing a
+// file not in sources, are left unmapped.
+func NewSourceMap(code []byte, srcMap string, sources []Source) (*SourceMap, error) {
+	var (
+		entries = strings.Split(srcMap, ";")
+		lines   = make([][]int, len(sources))
+		m       = &SourceMap{locs: make(map[uint64]SourceLocation)}
+
+		offset, length, file = 0, 0, -1
+	)
+	for i, src := range sources {
+		lines[i] = lineStarts(src.Code)
+	}
+	for i, pc := 0, uint64(0); pc < uint64(len(code)); i, pc = i+1, nextPC(code, pc) {
+		// The metadata appended to the code has no entries.
+		if i >= len(entries) {
+			break
+		}
+		// Empty fields, and missing trailing ones, repeat the previous entry.
+		fields := strings.Split(entries[i], ":")
+		for j, field := range fields {
+			if field == "" || j > 2 {
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source map entry %d: %q", i, entries[i])
+			}
+			switch j {
+			case 0:
+				offset = n
+			case 1:
+				length = n
+			case 2:
+				file = n
+			}
+		}
+		if file < 0 || file >= len(sources) || offset > len(sources[file].Code) {
+			continue
+		}
+		m.locs[pc] = SourceLocation{
+			File:   sources[file].Name,
+			Offset: offset,
+			Length: length,
+			Line:   lineOf(lines[file], offset),
+		}
+	}
+	return m, nil
+}
+
+// NewContractSourceMap builds the source map of the runtime code of a compiled
+// contract from its srcmap-runtime, resolved against the contract source.
+func NewContractSourceMap(name string, contract *compiler.Contract) (*SourceMap, error) {
+	if contract.Info.SrcMapRuntime == "" {
+		return nil, errors.New("contract has no runtime source map")
+	}
+	code := common.FromHex(contract.RuntimeCode)
+	return NewSourceMap(code, contract.Info.SrcMapRuntime, []Source{{Name: name, Code: contract.Info.Source}})
+}
+
+// Lookup returns the source location of the instruction at pc.
+func (m *SourceMap) Lookup(pc uint64) (SourceLocation, bool) {
+	loc, ok := m.locs[pc]
+	return loc, ok
+}
+
+// nextPC returns the program counter of the instruction following the one
+// at pc, skipping push data.
+func nextPC(code []byte, pc uint64) uint64 {
+	if op := vm.OpCode(code[pc]); op.IsPush() {
+		return pc + 1 + uint64(op-vm.PUSH0)
+	}
+	return pc + 1
+}
+
+// lineStarts returns the byte offsets at which the lines of src begin.
+func lineStarts(src string) []int {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineOf returns the 1-based line containing the byte offset.
+func lineOf(starts []int, offset int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+}
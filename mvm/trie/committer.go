@@ -0,0 +1,116 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// committer is the tool used for the trie Commit operation. It walks the
+// hashed trie and writes every dirty node which is referenced by hash into
+// the database.
+type committer struct {
+	w      ethdb.KeyValueWriter
+	encbuf rlp.EncoderBuffer
+}
+
+// Commit writes all modified nodes of the trie into w, keyed by their hash, and
+// returns the root hash. Nodes which are already persisted are not written
+// again, so committing a trie only costs the nodes changed since the last
+// commit. The trie stays usable after Commit.
+//
+// Nodes smaller than 32 bytes are embedded in their parent and are not stored
+// separately, except for the root node which is always stored.
+func (t *Trie) Commit(w ethdb.KeyValueWriter) (common.Hash, error) {
+	root := t.Hash()
+	if t.root == nil {
+		return types.EmptyRootHash, nil
+	}
+	c := &committer{w: w, encbuf: rlp.NewEncoderBuffer(nil)}
+	if _, err := c.commit(t.root); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+// commit collapses a node down into its stored form, writing it and its
+// children into the database on the way.
+func (c *committer) commit(n node) (node, error) {
+	hash, dirty := n.cache()
+	if hash != nil && !dirty {
+		return hash, nil
+	}
+	switch cn := n.(type) {
+	case *shortNode:
+		collapsed := cn.copy()
+		collapsed.Key = hexToCompact(cn.Key)
+		switch cn.Val.(type) {
+		case *fullNode, *shortNode:
+			child, err := c.commit(cn.Val)
+			if err != nil {
+				return nil, err
+			}
+			collapsed.Val = child
+		}
+		if hash == nil {
+			cn.flags.dirty = false
+			return collapsed, nil // embedded in the parent
+		}
+		collapsed.encode(c.encbuf)
+		if err := c.store(hash); err != nil {
+			return nil, err
+		}
+		cn.flags.dirty = false
+		return hash, nil
+	case *fullNode:
+		collapsed := cn.copy()
+		for i := 0; i < 16; i++ {
+			if cn.Children[i] == nil {
+				continue
+			}
+			child, err := c.commit(cn.Children[i])
+			if err != nil {
+				return nil, err
+			}
+			collapsed.Children[i] = child
+		}
+		if hash == nil {
+			cn.flags.dirty = false
+			return collapsed, nil // embedded in the parent
+		}
+		collapsed.encode(c.encbuf)
+		if err := c.store(hash); err != nil {
+			return nil, err
+		}
+		cn.flags.dirty = false
+		return hash, nil
+	default:
+		// Value and hash nodes are stored inside their parent
+		return n, nil
+	}
+}
+
+// store writes the last encoded node into the database under its hash, the
+// same layout rawdb.ReadTrieNode reads from.
+func (c *committer) store(hash hashNode) error {
+	blob := c.encbuf.ToBytes()
+	c.encbuf.Reset(nil)
+	return c.w.Put(hash, blob)
+}
@@ -0,0 +1,247 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/rawdb"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func TestEmptyTrie(t *testing.T) {
+	trie := NewEmpty()
+	res := trie.Hash()
+	exp := types.EmptyRootHash
+	if res != exp {
+		t.Errorf("expected %x got %x", exp, res)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	trie := NewEmpty()
+
+	updateString(trie, "doe", "reindeer")
+	updateString(trie, "dog", "puppy")
+	updateString(trie, "dogglesworth", "cat")
+
+	exp := common.HexToHash("8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3")
+	root := trie.Hash()
+	if root != exp {
+		t.Errorf("case 1: exp %x got %x", exp, root)
+	}
+
+	trie = NewEmpty()
+	updateString(trie, "A", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+
+	exp = common.HexToHash("d23786fb4a010da3ce639d66d5e904a11dbc02746d1ce25029e53290cabf28ab")
+	root = trie.Hash()
+	if root != exp {
+		t.Errorf("case 2: exp %x got %x", exp, root)
+	}
+}
+
+func TestGet(t *testing.T) {
+	trie := NewEmpty()
+	updateString(trie, "doe", "reindeer")
+	updateString(trie, "dog", "puppy")
+	updateString(trie, "dogglesworth", "cat")
+
+	for i := 0; i < 2; i++ {
+		res := getString(trie, "dog")
+		if !bytes.Equal(res, []byte("puppy")) {
+			t.Errorf("expected puppy got %x", res)
+		}
+		unknown := getString(trie, "unknown")
+		if unknown != nil {
+			t.Errorf("expected nil got %x", unknown)
+		}
+		// Lookups must keep working on a hashed trie.
+		trie.Hash()
+	}
+}
+
+func TestDelete(t *testing.T) {
+	trie := NewEmpty()
+	vals := []struct{ k, v string }{
+		{"do", "verb"},
+		{"ether", "wookiedoo"},
+		{"horse", "stallion"},
+		{"shaman", "horse"},
+		{"doge", "coin"},
+		{"ether", ""},
+		{"dog", "puppy"},
+		{"shaman", ""},
+	}
+	for _, val := range vals {
+		if val.v != "" {
+			updateString(trie, val.k, val.v)
+		} else {
+			deleteString(trie, val.k)
+		}
+	}
+
+	hash := trie.Hash()
+	exp := common.HexToHash("5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84")
+	if hash != exp {
+		t.Errorf("expected %x got %x", exp, hash)
+	}
+}
+
+func TestEmptyValues(t *testing.T) {
+	trie := NewEmpty()
+
+	vals := []struct{ k, v string }{
+		{"do", "verb"},
+		{"ether", "wookiedoo"},
+		{"horse", "stallion"},
+		{"shaman", "horse"},
+		{"doge", "coin"},
+		{"ether", ""},
+		{"dog", "puppy"},
+		{"shaman", ""},
+	}
+	for _, val := range vals {
+		updateString(trie, val.k, val.v)
+	}
+
+	hash := trie.Hash()
+	exp := common.HexToHash("5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84")
+	if hash != exp {
+		t.Errorf("expected %x got %x", exp, hash)
+	}
+}
+
+// TestInsertOrder checks that the root hash does not depend on the order in
+// which keys are inserted, nor on intermediate hashing.
+func TestInsertOrder(t *testing.T) {
+	keys := make([][]byte, 200)
+	for i := range keys {
+		keys[i] = common.Hash{byte(i), byte(i * 7), byte(i * 13)}.Bytes()
+	}
+	exp := NewEmpty()
+	for _, key := range keys {
+		exp.Update(key, key[:3])
+	}
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+
+	trie := NewEmpty()
+	for i, key := range keys {
+		trie.Update(key, key[:3])
+		if i%50 == 0 {
+			trie.Hash()
+		}
+	}
+	if have, want := trie.Hash(), exp.Hash(); have != want {
+		t.Errorf("root mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestMissingRoot(t *testing.T) {
+	root := common.HexToHash("0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33")
+	trie, err := New(root, memorydb.New())
+	if trie != nil {
+		t.Error("New returned non-nil trie for invalid root")
+	}
+	var missing *MissingNodeError
+	if !errors.As(err, &missing) {
+		t.Errorf("New returned wrong error: %v", err)
+	}
+}
+
+// TestCommitReopen checks that a committed trie can be reopened by its root,
+// and that nodes loaded on demand can be read, updated and deleted.
+func TestCommitReopen(t *testing.T) {
+	db := memorydb.New()
+	trie := NewEmpty()
+	for i := 0; i < 100; i++ {
+		key := common.Hash{byte(i), byte(i * 3)}
+		trie.Update(key[:], []byte{byte(i), 1})
+	}
+	updateString(trie, "dog", "puppy")
+	root, err := trie.Commit(db)
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if !rawdb.HasTrieNode(db, root) {
+		t.Fatalf("root node %x not stored", root)
+	}
+
+	reopened, err := New(root, db)
+	if err != nil {
+		t.Fatalf("can't reopen trie: %v", err)
+	}
+	if have := getString(reopened, "dog"); !bytes.Equal(have, []byte("puppy")) {
+		t.Errorf("wrong value for dog: %x", have)
+	}
+	for i := 0; i < 100; i++ {
+		key := common.Hash{byte(i), byte(i * 3)}
+		if have, _ := reopened.Get(key[:]); !bytes.Equal(have, []byte{byte(i), 1}) {
+			t.Errorf("wrong value for key %x: %x", key, have)
+		}
+	}
+	// Apply the same changes to the reopened trie and the in-memory one, the
+	// resulting roots must match.
+	for _, tr := range []*Trie{trie, reopened} {
+		deleteString(tr, "dog")
+		for i := 0; i < 100; i += 3 {
+			key := common.Hash{byte(i), byte(i * 3)}
+			tr.Delete(key[:])
+		}
+		updateString(tr, "horse", "stallion")
+	}
+	if have, want := reopened.Hash(), trie.Hash(); have != want {
+		t.Errorf("root mismatch after update: have %x, want %x", have, want)
+	}
+	// Committing again only writes the changed nodes.
+	size := db.Len()
+	root, err = reopened.Commit(db)
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if written := db.Len() - size; written == 0 || written > size/4 {
+		t.Errorf("unexpected number of nodes written: %d, first commit wrote %d", written, size)
+	}
+	again, err := New(root, db)
+	if err != nil {
+		t.Fatalf("can't reopen trie: %v", err)
+	}
+	if have := getString(again, "horse"); !bytes.Equal(have, []byte("stallion")) {
+		t.Errorf("wrong value for horse: %x", have)
+	}
+	if have := getString(again, "dog"); have != nil {
+		t.Errorf("deleted key still present: %x", have)
+	}
+}
+
+func getString(trie *Trie, k string) []byte {
+	v, _ := trie.Get([]byte(k))
+	return v
+}
+
+func updateString(trie *Trie, k, v string) {
+	trie.Update([]byte(k), []byte(v))
+}
+
+func deleteString(trie *Trie, k string) {
+	trie.Delete([]byte(k))
+}
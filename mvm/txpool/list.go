@@ -0,0 +1,94 @@
+package txpool
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/a1146910248/mixchain/mvm/types"
+)
+
+// list is a nonce indexed collection of the transactions of one account.
+type list struct {
+	items map[uint64]*types.Transaction
+	cache types.Transactions // Nonce sorted items, nil if invalidated
+}
+
+func newList() *list {
+	return &list{items: make(map[uint64]*types.Transaction)}
+}
+
+// Get retrieves the transaction with the given nonce, or nil.
+func (l *list) Get(nonce uint64) *types.Transaction {
+	return l.items[nonce]
+}
+
+// Put inserts a transaction, overwriting any previous one with the same nonce.
+func (l *list) Put(tx *types.Transaction) {
+	l.items[tx.Nonce()] = tx
+	l.cache = nil
+}
+
+// Remove deletes the transaction with the given nonce.
+func (l *list) Remove(nonce uint64) bool {
+	if _, ok := l.items[nonce]; !ok {
+		return false
+	}
+	delete(l.items, nonce)
+	l.cache = nil
+	return true
+}
+
+// Len returns the number of transactions in the list.
+func (l *list) Len() int {
+	return len(l.items)
+}
+
+// Empty returns whether the list is empty.
+func (l *list) Empty() bool {
+	return len(l.items) == 0
+}
+
+// Flatten returns the transactions sorted by nonce. The returned slice must not
+// be modified.
+func (l *list) Flatten() types.Transactions {
+	if l.cache == nil {
+		l.cache = make(types.Transactions, 0, len(l.items))
+		for _, tx := range l.items {
+			l.cache = append(l.cache, tx)
+		}
+		sort.Sort(types.TxByNonce(l.cache))
+	}
+	return l.cache
+}
+
+// Cost returns the cumulative cost of all transactions in the list.
+func (l *list) Cost() *big.Int {
+	cost := new(big.Int)
+	for _, tx := range l.items {
+		cost.Add(cost, tx.Cost())
+	}
+	return cost
+}
+
+// replaces reports whether tx is priced high enough to replace old, which
+// requires both the fee cap and the tip to be raised by at least priceBump
+// percent.
+func replaces(old, tx *types.Transaction, priceBump uint64) bool {
+	if old.GasFeeCapCmp(tx) >= 0 || old.GasTipCapCmp(tx) >= 0 {
+		return false
+	}
+	// thresholdFeeCap = oldFC  * (100 + priceBump) / 100
+	a := big.NewInt(100 + int64(priceBump))
+	aFeeCap := new(big.Int).Mul(a, old.GasFeeCap())
+	aTip := a.Mul(a, old.GasTipCap())
+
+	// thresholdTip    = oldTip * (100 + priceBump) / 100
+	b := big.NewInt(100)
+	thresholdFeeCap := aFeeCap.Div(aFeeCap, b)
+	thresholdTip := aTip.Div(aTip, b)
+
+	// We have to ensure that both the new fee cap and tip are higher than the
+	// old ones as well as checking the percentage threshold to ensure that
+	// this is accurate for low (Wei-level) gas price replacements.
+	return tx.GasFeeCapIntCmp(thresholdFeeCap) >= 0 && tx.GasTipCapIntCmp(thresholdTip) >= 0
+}
@@ -0,0 +1,402 @@
+// Package txpool keeps the transactions waiting to be included in a block.
+package txpool
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/common/prque"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// txSlotSize is used to calculate how many data slots a single transaction
+	// takes up based on its size.
+	txSlotSize = 32 * 1024
+
+	// txMaxSize is the maximum size a single transaction can have. This field has
+	// non-trivial consequences: larger transactions are significantly harder and
+	// more expensive to propagate; larger transactions also take more resources
+	// to validate whether they fit into the pool or not.
+	txMaxSize = 4 * txSlotSize // 128KB
+)
+
+// Config are the configuration parameters of the transaction pool.
+type Config struct {
+	PriceLimit uint64 // Minimum gas tip to enforce for acceptance into the pool
+	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
+
+	AccountSlots uint64 // Maximum number of transactions of a single account
+	GlobalSlots  uint64 // Maximum number of transactions in the pool
+}
+
+// DefaultConfig contains the default configurations for the transaction pool.
+var DefaultConfig = Config{
+	PriceLimit: 1,
+	PriceBump:  10,
+
+	AccountSlots: 64,
+	GlobalSlots:  4096,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.PriceLimit < 1 {
+		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultConfig.PriceLimit)
+		conf.PriceLimit = DefaultConfig.PriceLimit
+	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultConfig.PriceBump)
+		conf.PriceBump = DefaultConfig.PriceBump
+	}
+	if conf.AccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool account slots", "provided", conf.AccountSlots, "updated", DefaultConfig.AccountSlots)
+		conf.AccountSlots = DefaultConfig.AccountSlots
+	}
+	if conf.GlobalSlots < 1 {
+		log.Warn("Sanitizing invalid txpool global slots", "provided", conf.GlobalSlots, "updated", DefaultConfig.GlobalSlots)
+		conf.GlobalSlots = DefaultConfig.GlobalSlots
+	}
+	return conf
+}
+
+// TxPool contains all currently known transactions. Transactions enter the pool
+// when they are received from the API. They exit the pool when they are
+// included in the blockchain, evicted for a better priced one, or become
+// invalid after a new head.
+//
+// The pool separates processable transactions (which can be applied to the
+// current state) and future transactions. Transactions move between those
+// two states over time as they are received and processed.
+type TxPool struct {
+	config      Config
+	chainconfig *params.ChainConfig
+	signer      types.Signer
+	mu          sync.RWMutex
+
+	head  *types.Header  // Current head of the chain
+	state *state.StateDB // Current state in the blockchain head
+
+	pending map[common.Address]*list           // All currently processable transactions
+	queue   map[common.Address]*list           // Queued but non-processable transactions
+	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
+}
+
+// New creates a new transaction pool on top of the given head and its state.
+func New(config Config, chainconfig *params.ChainConfig, head *types.Header, statedb *state.StateDB) *TxPool {
+	return &TxPool{
+		config:      config.sanitize(),
+		chainconfig: chainconfig,
+		signer:      types.LatestSigner(chainconfig),
+		head:        head,
+		state:       statedb,
+		pending:     make(map[common.Address]*list),
+		queue:       make(map[common.Address]*list),
+		all:         make(map[common.Hash]*types.Transaction),
+	}
+}
+
+// Add validates the given transactions and inserts them into the pool, returning
+// an error for each rejected one.
+func (pool *TxPool) Add(txs []*types.Transaction) []error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = pool.add(tx)
+	}
+	return errs
+}
+
+// add validates a transaction and inserts it into the queue of its sender,
+// promoting it to pending if it's executable.
+func (pool *TxPool) add(tx *types.Transaction) error {
+	hash := tx.Hash()
+	if pool.all[hash] != nil {
+		log.Trace("Discarding already known transaction", "hash", hash)
+		return ErrAlreadyKnown
+	}
+	if err := pool.validateTx(tx); err != nil {
+		log.Trace("Discarding invalid transaction", "hash", hash, "err", err)
+		return err
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+
+	// If the transaction is replacing an already pooled one, do it directly
+	if old := pool.get(from, tx.Nonce()); old != nil {
+		if !replaces(old, tx, pool.config.PriceBump) {
+			return ErrReplaceUnderpriced
+		}
+		delete(pool.all, old.Hash())
+		if l := pool.pending[from]; l != nil && l.Get(tx.Nonce()) != nil {
+			l.Put(tx)
+		} else {
+			pool.queue[from].Put(tx)
+		}
+		pool.all[hash] = tx
+		log.Trace("Replaced pooled transaction", "hash", hash, "old", old.Hash(), "from", from)
+		return nil
+	}
+	// If the transaction pool is full, discard the cheapest transaction
+	if uint64(len(pool.all)) >= pool.config.GlobalSlots {
+		if err := pool.evict(tx); err != nil {
+			return err
+		}
+	}
+	if pool.queue[from] == nil {
+		pool.queue[from] = newList()
+	}
+	pool.queue[from].Put(tx)
+	pool.all[hash] = tx
+	pool.reorg(from)
+
+	log.Trace("Pooled new transaction", "hash", hash, "from", from, "to", tx.To())
+	return nil
+}
+
+// validateTx checks whether a transaction is valid according to the consensus
+// rules and adheres to some heuristic limits of the local node (price and size).
+func (pool *TxPool) validateTx(tx *types.Transaction) error {
+	opts := &ValidationOptions{
+		Config: pool.chainconfig,
+		Accept: 0 |
+			1<<types.LegacyTxType |
+			1<<types.AccessListTxType |
+			1<<types.DynamicFeeTxType,
+		MaxSize: txMaxSize,
+		MinTip:  new(big.Int).SetUint64(pool.config.PriceLimit),
+	}
+	if err := ValidateTransaction(tx, pool.head, pool.signer, opts); err != nil {
+		return err
+	}
+	stateOpts := &ValidationOptionsWithState{
+		State: pool.state,
+		UsedAndLeftSlots: func(addr common.Address) (int, int) {
+			used := pool.count(addr)
+			return used, int(pool.config.AccountSlots) - used
+		},
+		ExistingExpenditure: func(addr common.Address) *big.Int {
+			spent := new(big.Int)
+			if l := pool.pending[addr]; l != nil {
+				spent.Add(spent, l.Cost())
+			}
+			if l := pool.queue[addr]; l != nil {
+				spent.Add(spent, l.Cost())
+			}
+			return spent
+		},
+		ExistingCost: func(addr common.Address, nonce uint64) *big.Int {
+			if tx := pool.get(addr, nonce); tx != nil {
+				return tx.Cost()
+			}
+			return nil
+		},
+	}
+	return ValidateTransactionWithState(tx, pool.signer, stateOpts)
+}
+
+// evict makes room for tx by dropping the cheapest pooled transaction. It fails
+// if tx doesn't pay a higher tip than every pooled transaction.
+func (pool *TxPool) evict(tx *types.Transaction) error {
+	heap := prque.New[int64, *types.Transaction](nil)
+	for _, pooled := range pool.all {
+		heap.Push(pooled, -pool.tip(pooled))
+	}
+	cheapest, _ := heap.Peek()
+	if tx.EffectiveGasTipCmp(cheapest, pool.head.BaseFee) <= 0 {
+		return fmt.Errorf("%w: %w", ErrTxPoolOverflow, ErrUnderpriced)
+	}
+	from, _ := types.Sender(pool.signer, cheapest)
+	log.Trace("Evicting cheapest transaction", "hash", cheapest.Hash(), "from", from)
+
+	if l := pool.pending[from]; l != nil {
+		l.Remove(cheapest.Nonce())
+	}
+	if l := pool.queue[from]; l != nil {
+		l.Remove(cheapest.Nonce())
+	}
+	delete(pool.all, cheapest.Hash())
+	pool.reorg(from)
+	return nil
+}
+
+// tip returns the effective tip of the transaction at the current head, capped
+// to fit the priority queue.
+func (pool *TxPool) tip(tx *types.Transaction) int64 {
+	tip := tx.EffectiveGasTipValue(pool.head.BaseFee)
+	if !tip.IsInt64() {
+		if tip.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return tip.Int64()
+}
+
+// reorg rebuilds the lists of an account against the current state. Stale and
+// unpayable transactions are dropped, the transactions following the state
+// nonce without gaps are moved to pending, and the rest to the queue.
+func (pool *TxPool) reorg(addr common.Address) {
+	var txs types.Transactions
+	for _, lists := range []map[common.Address]*list{pool.pending, pool.queue} {
+		if l := lists[addr]; l != nil {
+			txs = append(txs, l.Flatten()...)
+		}
+	}
+	delete(pool.pending, addr)
+	delete(pool.queue, addr)
+
+	var (
+		nonce   = pool.state.GetNonce(addr)
+		balance = pool.state.GetBalance(addr).ToBig()
+		pending = newList()
+		queue   = newList()
+	)
+	sort.Sort(types.TxByNonce(txs))
+	for _, tx := range txs {
+		switch {
+		case tx.Nonce() < nonce:
+			log.Trace("Removed old transaction", "hash", tx.Hash())
+			delete(pool.all, tx.Hash())
+		case tx.Cost().Cmp(balance) > 0 || tx.Gas() > pool.head.GasLimit:
+			log.Trace("Removed unpayable transaction", "hash", tx.Hash())
+			delete(pool.all, tx.Hash())
+		case tx.Nonce() == nonce:
+			pending.Put(tx)
+			nonce++
+		default:
+			queue.Put(tx)
+		}
+	}
+	if !pending.Empty() {
+		pool.pending[addr] = pending
+	}
+	if !queue.Empty() {
+		pool.queue[addr] = queue
+	}
+}
+
+// Reset moves the pool to a new chain head. Transactions included in the
+// chain or no longer payable are dropped, and queued transactions which became
+// executable are promoted.
+func (pool *TxPool) Reset(head *types.Header, statedb *state.StateDB) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.head, pool.state = head, statedb
+
+	addrs := make(map[common.Address]struct{}, len(pool.pending)+len(pool.queue))
+	for addr := range pool.pending {
+		addrs[addr] = struct{}{}
+	}
+	for addr := range pool.queue {
+		addrs[addr] = struct{}{}
+	}
+	for addr := range addrs {
+		pool.reorg(addr)
+	}
+}
+
+// get returns the pooled transaction of the account with the given nonce.
+func (pool *TxPool) get(addr common.Address, nonce uint64) *types.Transaction {
+	if l := pool.pending[addr]; l != nil {
+		if tx := l.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if l := pool.queue[addr]; l != nil {
+		return l.Get(nonce)
+	}
+	return nil
+}
+
+// count returns the number of pooled transactions of the account.
+func (pool *TxPool) count(addr common.Address) int {
+	var n int
+	if l := pool.pending[addr]; l != nil {
+		n += l.Len()
+	}
+	if l := pool.queue[addr]; l != nil {
+		n += l.Len()
+	}
+	return n
+}
+
+// Get returns a transaction if it is contained in the pool and nil otherwise.
+func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.all[hash]
+}
+
+// Has returns an indicator whether txpool has a transaction cached with the
+// given hash.
+func (pool *TxPool) Has(hash common.Hash) bool {
+	return pool.Get(hash) != nil
+}
+
+// Nonce returns the next nonce of an account, with all transactions executable
+// by the pool already applied on top.
+func (pool *TxPool) Nonce(addr common.Address) uint64 {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	nonce := pool.state.GetNonce(addr)
+	if l := pool.pending[addr]; l != nil {
+		nonce += uint64(l.Len())
+	}
+	return nonce
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions.
+func (pool *TxPool) Stats() (int, int) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var pending, queued int
+	for _, l := range pool.pending {
+		pending += l.Len()
+	}
+	for _, l := range pool.queue {
+		queued += l.Len()
+	}
+	return pending, queued
+}
+
+// Pending retrieves all currently processable transactions, grouped by origin
+// account and sorted by nonce. The result can be passed to the block builder.
+func (pool *TxPool) Pending() map[common.Address][]*types.Transaction {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return flatten(pool.pending)
+}
+
+// Content retrieves the data content of the transaction pool, returning all the
+// pending as well as queued transactions, grouped by account and sorted by nonce.
+func (pool *TxPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return flatten(pool.pending), flatten(pool.queue)
+}
+
+func flatten(lists map[common.Address]*list) map[common.Address][]*types.Transaction {
+	txs := make(map[common.Address][]*types.Transaction, len(lists))
+	for addr, l := range lists {
+		txs[addr] = append([]*types.Transaction(nil), l.Flatten()...)
+	}
+	return txs
+}
@@ -0,0 +1,254 @@
+package txpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm"
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/holiman/uint256"
+)
+
+var (
+	testConfig = params.MergedTestChainConfig
+	testSigner = types.LatestSigner(testConfig)
+	testTo     = common.HexToAddress("0xdead")
+	testFunds  = uint256.NewInt(params.InitialBaseFee * 1_000_000)
+)
+
+func newTestHead() *types.Header {
+	return &types.Header{
+		Number:     big.NewInt(1),
+		Difficulty: new(big.Int),
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+}
+
+// setupPool creates a pool and a funded key.
+func setupPool(config Config) (*TxPool, *state.StateDB, *ecdsa.PrivateKey) {
+	key, _ := crypto.GenerateKey()
+	statedb := state.NewAccountStateDb()
+	statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), testFunds, tracing.BalanceChangeUnspecified)
+	return New(config, testConfig, newTestHead(), statedb), statedb, key
+}
+
+func fund(statedb *state.StateDB) *ecdsa.PrivateKey {
+	key, _ := crypto.GenerateKey()
+	statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), testFunds, tracing.BalanceChangeUnspecified)
+	return key
+}
+
+func pricedTx(key *ecdsa.PrivateKey, nonce uint64, gas uint64, tip, feeCap int64) *types.Transaction {
+	return types.MustSignNewTx(key, testSigner, &types.DynamicFeeTx{
+		ChainID:   testConfig.ChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(feeCap),
+		Gas:       gas,
+		To:        &testTo,
+		Value:     big.NewInt(1),
+	})
+}
+
+func transaction(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+	return pricedTx(key, nonce, params.TxGas, 1, params.InitialBaseFee*2)
+}
+
+func TestValidation(t *testing.T) {
+	pool, statedb, key := setupPool(DefaultConfig)
+	statedb.SetNonce(crypto.PubkeyToAddress(key.PublicKey), 1)
+
+	known := transaction(key, 1)
+	if err := pool.Add([]*types.Transaction{known})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	otherChain := types.MustSignNewTx(key, types.LatestSignerForChainID(big.NewInt(12345)), &types.DynamicFeeTx{
+		ChainID: big.NewInt(12345), Nonce: 2, GasTipCap: common.Big1, GasFeeCap: big.NewInt(params.InitialBaseFee), Gas: params.TxGas, To: &testTo,
+	})
+	blob := types.MustSignNewTx(key, testSigner, &types.BlobTx{
+		ChainID: uint256.MustFromBig(testConfig.ChainID), Nonce: 2, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(params.InitialBaseFee),
+		Gas: params.TxGas, BlobFeeCap: uint256.NewInt(1), BlobHashes: []common.Hash{{0x01}},
+	})
+	oversized := types.MustSignNewTx(key, testSigner, &types.DynamicFeeTx{
+		ChainID: testConfig.ChainID, Nonce: 2, GasTipCap: common.Big1, GasFeeCap: big.NewInt(params.InitialBaseFee), Gas: 10_000_000, To: &testTo,
+		Data: make([]byte, txMaxSize),
+	})
+	tests := []struct {
+		name string
+		tx   *types.Transaction
+		want error
+	}{
+		{"already known", known, ErrAlreadyKnown},
+		{"other chain", otherChain, ErrInvalidSender},
+		{"other chain id", otherChain, types.ErrInvalidChainId},
+		{"blob", blob, mvm.ErrTxTypeNotSupported},
+		{"oversized", oversized, ErrOversizedData},
+		{"block gas limit", pricedTx(key, 2, 30_000_001, 1, params.InitialBaseFee), ErrGasLimit},
+		{"intrinsic gas", pricedTx(key, 2, params.TxGas-1, 1, params.InitialBaseFee), mvm.ErrIntrinsicGas},
+		{"tip above fee cap", pricedTx(key, 2, params.TxGas, 2, 1), mvm.ErrTipAboveFeeCap},
+		{"no tip", pricedTx(key, 2, params.TxGas, 0, params.InitialBaseFee), ErrUnderpriced},
+		{"nonce too low", transaction(key, 0), mvm.ErrNonceTooLow},
+		{"insufficient funds", pricedTx(key, 2, 1_000_000, 1, params.InitialBaseFee), mvm.ErrInsufficientFunds},
+		{"unfunded sender", transaction(fund(state.NewAccountStateDb()), 0), mvm.ErrInsufficientFunds},
+	}
+	for _, tt := range tests {
+		if err := pool.Add([]*types.Transaction{tt.tx})[0]; !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// The cumulative cost of the pooled transactions counts against the balance.
+	half := pricedTx(key, 2, 500_000, 1, params.InitialBaseFee)
+	if err := pool.Add([]*types.Transaction{half})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.Add([]*types.Transaction{pricedTx(key, 3, 500_000, 1, params.InitialBaseFee)})[0]; !errors.Is(err, mvm.ErrInsufficientFunds) {
+		t.Errorf("overdraft error mismatch: have %v, want %v", err, mvm.ErrInsufficientFunds)
+	}
+}
+
+func TestQueuePromotion(t *testing.T) {
+	pool, _, key := setupPool(DefaultConfig)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	errs := pool.Add([]*types.Transaction{transaction(key, 2), transaction(key, 1)})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 2 {
+		t.Fatalf("stats mismatch: pending %d, queued %d", pending, queued)
+	}
+	if nonce := pool.Nonce(addr); nonce != 0 {
+		t.Errorf("nonce mismatch: have %d, want 0", nonce)
+	}
+	// Filling the gap promotes the queued transactions.
+	if err := pool.Add([]*types.Transaction{transaction(key, 0)})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("stats mismatch: pending %d, queued %d", pending, queued)
+	}
+	if nonce := pool.Nonce(addr); nonce != 3 {
+		t.Errorf("nonce mismatch: have %d, want 3", nonce)
+	}
+	txs := pool.Pending()[addr]
+	for i, tx := range txs {
+		if tx.Nonce() != uint64(i) {
+			t.Errorf("pending transaction %d has nonce %d", i, tx.Nonce())
+		}
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	pool, _, key := setupPool(DefaultConfig)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	var (
+		pending = pricedTx(key, 0, params.TxGas, 100, params.InitialBaseFee*2)
+		queued  = pricedTx(key, 5, params.TxGas, 100, params.InitialBaseFee*2)
+	)
+	for _, err := range pool.Add([]*types.Transaction{pending, queued}) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	for _, old := range []*types.Transaction{pending, queued} {
+		// Both the fee cap and the tip have to be bumped by 10 percent.
+		tests := []struct {
+			tip, feeCap int64
+			want        error
+		}{
+			{109, params.InitialBaseFee * 3, ErrReplaceUnderpriced},
+			{200, params.InitialBaseFee*2 + 1, ErrReplaceUnderpriced},
+			{110, params.InitialBaseFee * 22 / 10, nil},
+		}
+		var replacement *types.Transaction
+		for i, tt := range tests {
+			replacement = pricedTx(key, old.Nonce(), params.TxGas, tt.tip, tt.feeCap)
+			if err := pool.Add([]*types.Transaction{replacement})[0]; !errors.Is(err, tt.want) {
+				t.Errorf("nonce %d, test %d: error mismatch: have %v, want %v", old.Nonce(), i, err, tt.want)
+			}
+		}
+		if pool.Has(old.Hash()) || !pool.Has(replacement.Hash()) {
+			t.Errorf("nonce %d: transaction not replaced", old.Nonce())
+		}
+	}
+	pendingTxs, queuedTxs := pool.Content()
+	if len(pendingTxs[addr]) != 1 || len(queuedTxs[addr]) != 1 {
+		t.Errorf("content mismatch: pending %d, queued %d", len(pendingTxs[addr]), len(queuedTxs[addr]))
+	}
+}
+
+func TestEviction(t *testing.T) {
+	config := DefaultConfig
+	config.GlobalSlots = 3
+	pool, statedb, _ := setupPool(config)
+
+	keys := []*ecdsa.PrivateKey{fund(statedb), fund(statedb), fund(statedb), fund(statedb), fund(statedb)}
+	cheapest := pricedTx(keys[0], 0, params.TxGas, 1, params.InitialBaseFee*2)
+	for _, err := range pool.Add([]*types.Transaction{
+		cheapest,
+		pricedTx(keys[1], 0, params.TxGas, 2, params.InitialBaseFee*2),
+		pricedTx(keys[2], 0, params.TxGas, 3, params.InitialBaseFee*2),
+	}) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if err := pool.Add([]*types.Transaction{pricedTx(keys[3], 0, params.TxGas, 1, params.InitialBaseFee*2)})[0]; !errors.Is(err, ErrUnderpriced) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	expensive := pricedTx(keys[4], 0, params.TxGas, 5, params.InitialBaseFee*2)
+	if err := pool.Add([]*types.Transaction{expensive})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if pool.Has(cheapest.Hash()) || !pool.Has(expensive.Hash()) {
+		t.Errorf("cheapest transaction not evicted")
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Errorf("stats mismatch: pending %d, queued %d", pending, queued)
+	}
+}
+
+func TestReset(t *testing.T) {
+	pool, statedb, key := setupPool(DefaultConfig)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	txs := []*types.Transaction{transaction(key, 0), transaction(key, 1), transaction(key, 3)}
+	for _, err := range pool.Add(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// The first two transactions got included, and a transaction with nonce 2
+	// from elsewhere as well.
+	statedb.SetNonce(addr, 3)
+	head := newTestHead()
+	head.Number = big.NewInt(2)
+	pool.Reset(head, statedb)
+
+	if pool.Has(txs[0].Hash()) || pool.Has(txs[1].Hash()) {
+		t.Errorf("included transactions not dropped")
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Errorf("stats mismatch: pending %d, queued %d", pending, queued)
+	}
+	if nonce := pool.Nonce(addr); nonce != 4 {
+		t.Errorf("nonce mismatch: have %d, want 4", nonce)
+	}
+	// Spending the funds elsewhere invalidates the pooled transaction.
+	statedb.SubBalance(addr, statedb.GetBalance(addr), tracing.BalanceChangeUnspecified)
+	pool.Reset(head, statedb)
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Errorf("stats mismatch: pending %d, queued %d", pending, queued)
+	}
+}
@@ -20,8 +20,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/big"
+	"slices"
 
 	"github.com/a1146910248/mixchain/crypto"
+	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/common/hexutil"
 )
 
@@ -158,3 +160,21 @@ func bloomValues(data []byte, hashbuf []byte) (uint, byte, uint, byte, uint, byt
 func BloomLookup(bin Bloom, topic bytesBacked) bool {
 	return bin.Test(topic.Bytes())
 }
+
+// BloomFilter checks whether a block may contain logs matching the filter
+// criteria, according to its bloom.
+func BloomFilter(bloom Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 && !slices.ContainsFunc(addresses, func(addr common.Address) bool {
+		return BloomLookup(bloom, addr)
+	}) {
+		return false
+	}
+	for _, sub := range topics {
+		if len(sub) > 0 && !slices.ContainsFunc(sub, func(topic common.Hash) bool {
+			return BloomLookup(bloom, topic)
+		}) {
+			return false
+		}
+	}
+	return true
+}
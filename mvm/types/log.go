@@ -17,6 +17,8 @@
 package types
 
 import (
+	"slices"
+
 	"github.com/a1146910248/mixchain/mvm/common"
 	"github.com/a1146910248/mixchain/mvm/common/hexutil"
 )
@@ -59,3 +61,28 @@ type logMarshaling struct {
 	TxIndex     hexutil.Uint
 	Index       hexutil.Uint
 }
+
+// FilterLogs creates a slice of logs matching the given criteria.
+func FilterLogs(logs []*Log, addresses []common.Address, topics [][]common.Hash) []*Log {
+	var ret []*Log
+Logs:
+	for _, log := range logs {
+		if len(addresses) > 0 && !slices.Contains(addresses, log.Address) {
+			continue
+		}
+		// If the to filtered topics is greater than the amount of topics in logs, skip.
+		if len(topics) > len(log.Topics) {
+			continue
+		}
+		for i, sub := range topics {
+			if len(sub) == 0 {
+				continue // empty rule set == wildcard
+			}
+			if !slices.Contains(sub, log.Topics[i]) {
+				continue Logs
+			}
+		}
+		ret = append(ret, log)
+	}
+	return ret
+}
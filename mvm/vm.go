@@ -2,6 +2,8 @@ package mvm
 
 import (
 	"github.com/a1146910248/mixchain/mvm/common"
+	cmath "github.com/a1146910248/mixchain/mvm/common/math"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip4844"
 	"github.com/a1146910248/mixchain/mvm/tracing"
 	"github.com/a1146910248/mixchain/mvm/types"
 	"github.com/a1146910248/mixchain/mvm/vm"
@@ -32,8 +34,41 @@ type Message struct {
 	SkipAccountChecks bool
 }
 
-// NewEVMBlockContext creates a new context for use in the EVM.
-func NewEVMBlockContext(header *types.Header) vm.BlockContext {
+// TransactionToMessage converts a transaction into a Message.
+func TransactionToMessage(tx *types.Transaction, s types.Signer, baseFee *big.Int) (*Message, error) {
+	msg := &Message{
+		Nonce:             tx.Nonce(),
+		GasLimit:          tx.Gas(),
+		GasPrice:          new(big.Int).Set(tx.GasPrice()),
+		GasFeeCap:         new(big.Int).Set(tx.GasFeeCap()),
+		GasTipCap:         new(big.Int).Set(tx.GasTipCap()),
+		To:                tx.To(),
+		Value:             tx.Value(),
+		Data:              tx.Data(),
+		AccessList:        tx.AccessList(),
+		SkipAccountChecks: false,
+		BlobHashes:        tx.BlobHashes(),
+		BlobGasFeeCap:     tx.BlobGasFeeCap(),
+	}
+	// If baseFee provided, set gasPrice to effectiveGasPrice.
+	if baseFee != nil {
+		msg.GasPrice = cmath.BigMin(msg.GasPrice.Add(msg.GasTipCap, baseFee), msg.GasFeeCap)
+	}
+	var err error
+	msg.From, err = types.Sender(s, tx)
+	return msg, err
+}
+
+// HeaderReader supports retrieving headers from the current chain to be used
+// during transaction processing.
+type HeaderReader interface {
+	// GetHeader returns the header corresponding to the hash/number argument pair.
+	GetHeader(hash common.Hash, number uint64) *types.Header
+}
+
+// NewEVMBlockContext creates a new context for use in the EVM. The chain is
+// used to look up ancestor hashes for BLOCKHASH and may be nil.
+func NewEVMBlockContext(header *types.Header, chain HeaderReader) vm.BlockContext {
 	var (
 		baseFee     *big.Int
 		blobBaseFee *big.Int
@@ -44,7 +79,7 @@ func NewEVMBlockContext(header *types.Header) vm.BlockContext {
 		baseFee = new(big.Int).Set(header.BaseFee)
 	}
 	if header.ExcessBlobGas != nil {
-		blobBaseFee = new(big.Int)
+		blobBaseFee = eip4844.CalcBlobFee(*header.ExcessBlobGas)
 	}
 	if header.Difficulty.Sign() == 0 {
 		random = &header.MixDigest
@@ -52,7 +87,7 @@ func NewEVMBlockContext(header *types.Header) vm.BlockContext {
 	return vm.BlockContext{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
-		GetHash:     GetHashFn(),
+		GetHash:     GetHashFn(header, chain),
 		Coinbase:    header.Coinbase,
 		BlockNumber: new(big.Int).Set(header.Number),
 		Time:        header.Time,
@@ -77,41 +112,49 @@ func NewEVMTxContext(msg *Message) vm.TxContext {
 	return ctx
 }
 
-// GetHashFn returns a GetHashFunc which retrieves header hashes by number
-func GetHashFn() func(n uint64) common.Hash {
+// GetHashFn returns a GetHashFunc which retrieves header hashes by number.
+// Only the 256 most recent ancestors of ref can be looked up. If chain is nil,
+// only the parent hash of ref is known.
+func GetHashFn(ref *types.Header, chain HeaderReader) func(n uint64) common.Hash {
 	// Cache will initially contain [refHash.parent],
 	// Then fill up with [refHash.p, refHash.pp, refHash.ppp, ...]
-	//var cache []common.Hash
+	var cache []common.Hash
 
 	return func(n uint64) common.Hash {
-		//if ref.Number.Uint64() <= n {
-		//	// This situation can happen if we're doing tracing and using
-		//	// block overrides.
-		//	return common.Hash{}
-		//}
-		//// If there's no hash cache yet, make one
-		//if len(cache) == 0 {
-		//	cache = append(cache, ref.ParentHash)
-		//}
-		//if idx := ref.Number.Uint64() - n - 1; idx < uint64(len(cache)) {
-		//	return cache[idx]
-		//}
-		//// No luck in the cache, but we can start iterating from the last element we already know
-		//lastKnownHash := cache[len(cache)-1]
-		//lastKnownNumber := ref.Number.Uint64() - uint64(len(cache))
-		//
-		//for {
-		//	header := chain.GetHeader(lastKnownHash, lastKnownNumber)
-		//	if header == nil {
-		//		break
-		//	}
-		//	cache = append(cache, header.ParentHash)
-		//	lastKnownHash = header.ParentHash
-		//	lastKnownNumber = header.Number.Uint64() - 1
-		//	if n == lastKnownNumber {
-		//		return lastKnownHash
-		//	}
-		//}
+		if ref.Number.Uint64() <= n {
+			// This situation can happen if we're doing tracing and using
+			// block overrides.
+			return common.Hash{}
+		}
+		if ref.Number.Uint64()-n > 256 {
+			return common.Hash{}
+		}
+		// If there's no hash cache yet, make one
+		if len(cache) == 0 {
+			cache = append(cache, ref.ParentHash)
+		}
+		if idx := ref.Number.Uint64() - n - 1; idx < uint64(len(cache)) {
+			return cache[idx]
+		}
+		if chain == nil {
+			return common.Hash{}
+		}
+		// No luck in the cache, but we can start iterating from the last element we already know
+		lastKnownHash := cache[len(cache)-1]
+		lastKnownNumber := ref.Number.Uint64() - uint64(len(cache))
+
+		for {
+			header := chain.GetHeader(lastKnownHash, lastKnownNumber)
+			if header == nil {
+				break
+			}
+			cache = append(cache, header.ParentHash)
+			lastKnownHash = header.ParentHash
+			lastKnownNumber = header.Number.Uint64() - 1
+			if n == lastKnownNumber {
+				return lastKnownHash
+			}
+		}
 		return common.Hash{}
 	}
 }
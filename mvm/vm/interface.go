@@ -45,6 +45,7 @@ type StateDB interface {
 	AddRefund(uint64)
 	SubRefund(uint64)
 	GetRefund() uint64
+	CappedRefund(gasUsed uint64) uint64
 
 	GetCommittedState(common.Address, common.Hash) common.Hash
 	GetState(common.Address, common.Hash) common.Hash
@@ -0,0 +1,162 @@
+package mvm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/a1146910248/mixchain/mvm/common"
+	"github.com/a1146910248/mixchain/mvm/consensus/misc/eip4844"
+	"github.com/a1146910248/mixchain/mvm/params"
+	"github.com/a1146910248/mixchain/mvm/state"
+	"github.com/a1146910248/mixchain/mvm/tracing"
+	"github.com/a1146910248/mixchain/mvm/types"
+	"github.com/a1146910248/mixchain/mvm/vm"
+	"github.com/holiman/uint256"
+)
+
+// countingReader counts the header lookups hitting the underlying store.
+type countingReader struct {
+	*MemoryHeaderStore
+	lookups int
+}
+
+func (r *countingReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	r.lookups++
+	return r.MemoryHeaderStore.GetHeader(hash, number)
+}
+
+// makeHeaderChain returns a store with n linked headers and the header
+// following the last one.
+func makeHeaderChain(n int) (*MemoryHeaderStore, []*types.Header) {
+	var (
+		store   = NewMemoryHeaderStore()
+		headers []*types.Header
+		parent  common.Hash
+	)
+	for i := 0; i <= n; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Difficulty: new(big.Int),
+			Extra:      []byte("mixchain"),
+		}
+		if i < n {
+			store.AddHeader(header)
+		}
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	return store, headers
+}
+
+func TestGetHashFn(t *testing.T) {
+	store, headers := makeHeaderChain(300)
+	var (
+		ref     = headers[300]
+		reader  = &countingReader{MemoryHeaderStore: store}
+		getHash = GetHashFn(ref, reader)
+	)
+	for _, n := range []uint64{299, 298, 200, 44} {
+		if have, want := getHash(n), headers[n].Hash(); have != want {
+			t.Errorf("hash of block %d mismatch: have %x, want %x", n, have, want)
+		}
+	}
+	// Lookups of already visited ancestors are served from the cache.
+	lookups := reader.lookups
+	getHash(250)
+	getHash(100)
+	if reader.lookups != lookups {
+		t.Errorf("cached lookups hit the store: %d new lookups", reader.lookups-lookups)
+	}
+	// The reference block, its descendants and blocks older than 256 are unknown.
+	for _, n := range []uint64{300, 301, 43, 0} {
+		if have := getHash(n); have != (common.Hash{}) {
+			t.Errorf("hash of block %d should be unknown, have %x", n, have)
+		}
+	}
+	// Without a chain only the parent hash is available.
+	getHash = GetHashFn(ref, nil)
+	if have := getHash(299); have != ref.ParentHash {
+		t.Errorf("parent hash mismatch: have %x, want %x", have, ref.ParentHash)
+	}
+	if have := getHash(298); have != (common.Hash{}) {
+		t.Errorf("hash of block 298 should be unknown, have %x", have)
+	}
+}
+
+func TestBlockhashOpcode(t *testing.T) {
+	var (
+		store, headers = makeHeaderChain(10)
+		statedb        = state.NewAccountStateDb()
+		to             = common.HexToAddress("0xb10c")
+		// sstore(0, blockhash(7))
+		code = common.FromHex("600740600055")
+		msg  = newTestMessage(&to, 0, 100000, nil)
+	)
+	statedb.AddBalance(testSender, uint256.NewInt(params.InitialBaseFee*200000), tracing.BalanceChangeUnspecified)
+	statedb.SetCode(to, code)
+
+	header := headers[10]
+	header.BaseFee = testBaseFee
+	header.GasLimit = 30_000_000
+	evm := vm.NewEVM(NewEVMBlockContext(header, store), NewEVMTxContext(msg), statedb, params.MergedTestChainConfig, vm.Config{})
+	if _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(30_000_000)); err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if have, want := statedb.GetState(to, common.Hash{}), headers[7].Hash(); have != want {
+		t.Errorf("blockhash mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestMemoryHeaderStore(t *testing.T) {
+	store, headers := makeHeaderChain(3)
+	if have := store.GetHeader(headers[1].Hash(), 1); have == nil || have.Hash() != headers[1].Hash() {
+		t.Errorf("header 1 not found by hash and number")
+	}
+	if have := store.GetHeader(headers[1].Hash(), 2); have != nil {
+		t.Errorf("header returned for wrong number")
+	}
+	if have := store.GetHeaderByHash(headers[3].Hash()); have != nil {
+		t.Errorf("unknown header returned")
+	}
+	// A sibling replaces the header returned by number.
+	sibling := types.CopyHeader(headers[2])
+	sibling.Extra = []byte("sibling")
+	store.AddHeader(sibling)
+	if have := store.GetHeaderByNumber(2); have == nil || have.Hash() != sibling.Hash() {
+		t.Errorf("sibling not returned by number")
+	}
+	if have := store.GetHeaderByHash(headers[2].Hash()); have == nil {
+		t.Errorf("replaced header no longer available by hash")
+	}
+}
+
+func TestFeeOpcodes(t *testing.T) {
+	var (
+		statedb = state.NewAccountStateDb()
+		to      = common.HexToAddress("0xfee")
+		// sstore(0, basefee) sstore(1, blobbasefee)
+		code          = common.FromHex("486000554a600155")
+		msg           = newTestMessage(&to, 0, 100000, nil)
+		excessBlobGas = uint64(10 * params.BlobTxTargetBlobGasPerBlock)
+	)
+	statedb.AddBalance(testSender, uint256.NewInt(params.InitialBaseFee*200000), tracing.BalanceChangeUnspecified)
+	statedb.SetCode(to, code)
+
+	evm := newTestEVM(statedb, msg)
+	header := &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), BaseFee: testBaseFee, ExcessBlobGas: &excessBlobGas}
+	evm.Context = NewEVMBlockContext(header, nil)
+	if _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(30_000_000)); err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if have := statedb.GetState(to, common.Hash{}).Big(); have.Cmp(testBaseFee) != 0 {
+		t.Errorf("basefee mismatch: have %v, want %v", have, testBaseFee)
+	}
+	want := eip4844.CalcBlobFee(excessBlobGas)
+	if want.Cmp(common.Big1) <= 0 {
+		t.Fatalf("blob fee not above the minimum: %v", want)
+	}
+	if have := statedb.GetState(to, common.Hash{31: 1}).Big(); have.Cmp(want) != 0 {
+		t.Errorf("blobbasefee mismatch: have %v, want %v", have, want)
+	}
+}